@@ -0,0 +1,180 @@
+package minidoc
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const collectionBucketName = "_collections"
+
+// SaveCollection stores docids, e.g. note:3, under name in the given order, an existing collection is replaced
+func (tx *BucketTx) SaveCollection(name string, docids []string) error {
+	data, err := json.Marshal(docids)
+	if err != nil {
+		log.Errorf("error while marshalling collection %s: %v", name, err)
+		return err
+	}
+	return tx.kv.Put(collectionBucketName, []byte(name), data)
+}
+
+// Collection returns the docids of the collection called name in their stored order
+func (tx *BucketTx) Collection(name string) ([]string, error) {
+	data := tx.kv.Get(collectionBucketName, []byte(name))
+	if data == nil {
+		return nil, fmt.Errorf("no collection named %s", name)
+	}
+	docids := []string{}
+	err := json.Unmarshal(data, &docids)
+	if err != nil {
+		log.Errorf("error while unmarshalling collection %s: %v", name, err)
+		return nil, err
+	}
+	return docids, nil
+}
+
+// DeleteCollection removes the collection called name, the docs in it are left alone
+func (tx *BucketTx) DeleteCollection(name string) error {
+	if tx.kv.Get(collectionBucketName, []byte(name)) == nil {
+		return fmt.Errorf("no collection named %s", name)
+	}
+	return tx.kv.Delete(collectionBucketName, []byte(name))
+}
+
+// CollectionDocs reads the docs of the collection called name in order, docs deleted since are skipped
+func (tx *BucketTx) CollectionDocs(name string) ([]MiniDoc, error) {
+	docids, err := tx.Collection(name)
+	if err != nil {
+		return nil, err
+	}
+
+	docs := []MiniDoc{}
+	for _, docid := range docids {
+		parts := strings.Split(docid, ":")
+		if len(parts) != 2 {
+			log.Errorf("malformed doc id %s in collection %s", docid, name)
+			continue
+		}
+		id, err := strconv.Atoi(parts[1])
+		if err != nil {
+			log.Errorf("malformed doc id %s in collection %s", docid, name)
+			continue
+		}
+		doc, err := tx.Read(uint32(id), parts[0])
+		if err != nil {
+			log.Debugf("%s in collection %s is gone: %v", docid, name, err)
+			continue
+		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+// SaveCollection stores the selected rows, or every row when none is selected, as a collection in their current order
+func (s *Search) SaveCollection(name string) {
+	docs, err := s.SelectedDocs()
+	if err != nil {
+		s.App.SetStatus("[black:red]saving collection: " + err.Error() + "[white]")
+		return
+	}
+	if len(docs) == 0 {
+		for i := 0; i < s.ResultList.GetRowCount(); i++ {
+			doc, err := s.LoadMiniDocFromDB(i)
+			if err != nil {
+				log.Errorf("minidoc from failed: %v", err)
+				return
+			}
+			docs = append(docs, doc)
+		}
+	}
+	if len(docs) == 0 {
+		s.App.SetStatus("[black:red]no docs to put in collection " + name + "[white]")
+		return
+	}
+
+	if err := s.writeCollection(name, docs); err != nil {
+		s.App.SetStatus("[black:red]saving collection: " + err.Error() + "[white]")
+		return
+	}
+	s.ShowCollection(name)
+}
+
+// ShowCollection lists the docs of the collection called name in their stored order, all selected so that
+// @generate and @export use them in that order, moving rows with Ctrl-j and Ctrl-k updates the stored order
+func (s *Search) ShowCollection(name string) {
+	var docs []MiniDoc
+	err := s.App.DataHandler.Store.View(func(tx *BucketTx) error {
+		var err error
+		docs, err = tx.CollectionDocs(name)
+		return err
+	})
+	if err != nil {
+		s.App.SetStatus("[black:red]" + err.Error() + "[white]")
+		return
+	}
+
+	s.UpdateResult([]MiniDoc{})
+	s.Collection = name
+	for i, doc := range docs {
+		doc.SetIsSelected(true)
+		doc.SetSearchFragments(doc.GetTitle())
+		s.ResultList.UpdateRow(i, doc)
+	}
+	s.ResultList.SetTitle("Collection " + name)
+
+	s.ResultList.ScrollToBeginning()
+	s.SelectRow(0)
+	s.GoToSearchResult()
+	s.App.SetStatus(fmt.Sprintf("[white:darkcyan] %d docs in collection %s | Ctrl-j, Ctrl-k <- reorder[white]", len(docs), name))
+}
+
+// IsCollectionMode tells whether the result list is showing a collection
+func (s *Search) IsCollectionMode() bool {
+	return len(s.Collection) > 0
+}
+
+// SaveCollectionOrder stores the rows of the collection shown in their current order
+func (s *Search) SaveCollectionOrder() {
+	docs := []MiniDoc{}
+	for i := 0; i < s.ResultList.GetRowCount(); i++ {
+		doc, err := s.LoadMiniDocFromDB(i)
+		if err != nil {
+			log.Errorf("minidoc from failed: %v", err)
+			return
+		}
+		docs = append(docs, doc)
+	}
+	if err := s.writeCollection(s.Collection, docs); err != nil {
+		s.App.SetStatus("[black:red]saving collection order: " + err.Error() + "[white]")
+	}
+}
+
+func (s *Search) writeCollection(name string, docs []MiniDoc) error {
+	docids := make([]string, len(docs))
+	for i, doc := range docs {
+		docids[i] = doc.GetIDString()
+	}
+	err := s.App.DataHandler.Store.Update(func(tx *BucketTx) error {
+		return tx.SaveCollection(name, docids)
+	})
+	if err != nil {
+		log.Errorf("saving collection %s: %v", name, err)
+	}
+	return err
+}
+
+// DeleteCollection removes the collection called name
+func (s *Search) DeleteCollection(name string) {
+	err := s.App.DataHandler.Store.Update(func(tx *BucketTx) error {
+		return tx.DeleteCollection(name)
+	})
+	if err != nil {
+		s.App.SetStatus("[black:red]removing collection: " + err.Error() + "[white]")
+		return
+	}
+	if s.Collection == name {
+		s.UpdateResult([]MiniDoc{})
+	}
+	s.App.SetStatus("[white:darkcyan] collection " + name + " removed[white]")
+}
@@ -0,0 +1,53 @@
+package minidoc
+
+import (
+	"testing"
+)
+
+func TestBucketTx_Collection(t *testing.T) {
+	db := NewMemStore()
+
+	note := GetTestNoteMiniDoc()
+	todo := GetTestTodoMiniDoc()
+	gone := GetTestNoteMiniDoc()
+	db.Update(func(tx *BucketTx) error {
+		for _, doc := range []MiniDoc{note, todo, gone} {
+			id, err := tx.Write(doc)
+			if err != nil {
+				return err
+			}
+			doc.SetID(id)
+		}
+		return tx.SaveCollection("talk", []string{todo.GetIDString(), gone.GetIDString(), note.GetIDString()})
+	})
+	db.Update(func(tx *BucketTx) error {
+		return tx.Delete(gone)
+	})
+
+	var docs []MiniDoc
+	err := db.View(func(tx *BucketTx) error {
+		var err error
+		docs, err = tx.CollectionDocs("talk")
+		return err
+	})
+	if err != nil || len(docs) != 2 || docs[0].GetIDString() != todo.GetIDString() || docs[1].GetIDString() != note.GetIDString() {
+		t.Logf("expected the todo then the note but got %v: %v", docs, err)
+		t.Fail()
+	}
+
+	err = db.Update(func(tx *BucketTx) error {
+		return tx.DeleteCollection("talk")
+	})
+	if err != nil {
+		t.Logf("deleting collection: %v", err)
+		t.Fail()
+	}
+	err = db.View(func(tx *BucketTx) error {
+		_, err := tx.Collection("talk")
+		return err
+	})
+	if err == nil {
+		t.Log("reading a deleted collection should fail")
+		t.Fail()
+	}
+}
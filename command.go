@@ -12,6 +12,9 @@ import (
 	"time"
 )
 
+// noArgCommands are run even when nothing follows the verb
+var noArgCommands = []string{"history", "trash"}
+
 func (s *Search) HandleCommand(command string) {
 	// remove @symbol
 	command = command[1:]
@@ -21,7 +24,7 @@ func (s *Search) HandleCommand(command string) {
 	log.Debugf("command terms %s", terms)
 
 	// if only @verb is present, don't process further
-	if len(terms) == 1 {
+	if len(terms) == 1 && !contains(noArgCommands, verb) {
 		return
 	}
 
@@ -130,7 +133,7 @@ func (s *Search) HandleCommand(command string) {
 
 	case "list":
 		doctype := terms[1]
-		docs, err := s.App.DataHandler.BucketHandler.ReadAll(doctype)
+		docs, err := s.App.DataHandler.Store.ReadAll(doctype)
 		if err != nil {
 			log.Errorf("error reading docs by type: %v", err)
 			return
@@ -149,18 +152,11 @@ func (s *Search) HandleCommand(command string) {
 		s.App.SetStatus("[white:darkcyan] listing docs by type '" + doctype + "'")
 	case "tag":
 		tags := terms[1:]
-		for i := 0; i < s.ResultList.GetRowCount(); i++ {
-			log.Debugf("current row %d", s.CurrentRowIndex)
-			doc, err := s.LoadMiniDocFromDB(i)
-			if err != nil {
-				log.Errorf("minidoc from failed: %v", err)
-				return
-			}
-
-			if !doc.IsSelected() {
-				log.Debugf("row %d not selected skipping", i)
-				continue
-			}
+		docs, err := s.SelectedDocs()
+		if err != nil {
+			return
+		}
+		for _, doc := range docs {
 			dtags := strings.Split(doc.GetTags(), " ")
 			str := ""
 			for _, tag := range tags {
@@ -171,24 +167,21 @@ func (s *Search) HandleCommand(command string) {
 			str = strings.Join(dtags, " ") + str
 			str = strings.TrimSpace(str)
 			doc.SetTags(str)
-			s.App.DataHandler.Write(doc)
+		}
+		err = s.App.DataHandler.WriteAll(docs)
+		if err != nil {
+			log.Errorf("minidoc write failed: %v", err)
+			s.App.SetStatus("[black:red]tagging: " + err.Error() + "[white]")
+			return
 		}
 		s.App.SetStatus("[white]tagged[white]")
 	case "untag":
 		tags := terms[1:]
-		for i := 0; i < s.ResultList.GetRowCount(); i++ {
-			log.Debugf("current row %d", s.CurrentRowIndex)
-			doc, err := s.LoadMiniDocFromDB(i)
-			if err != nil {
-				log.Errorf("minidoc from failed: %v", err)
-				return
-			}
-
-			if !doc.IsSelected() {
-				log.Debugf("row %d not selected skipping", i)
-				continue
-			}
-
+		docs, err := s.SelectedDocs()
+		if err != nil {
+			return
+		}
+		for _, doc := range docs {
 			dtags := strings.Split(doc.GetTags(), " ")
 			log.Debugf("dtags: %v", dtags)
 			str := ""
@@ -200,12 +193,12 @@ func (s *Search) HandleCommand(command string) {
 			str = strings.TrimSpace(str)
 			log.Debugf("str: [%s]", str)
 			doc.SetTags(str)
-			_, err = s.App.DataHandler.Write(doc)
-			if err != nil {
-				log.Errorf("minidoc write failed: %v", err)
-				return
-			}
-
+		}
+		err = s.App.DataHandler.WriteAll(docs)
+		if err != nil {
+			log.Errorf("minidoc write failed: %v", err)
+			s.App.SetStatus("[black:red]untagging: " + err.Error() + "[white]")
+			return
 		}
 		s.App.SetStatus("[white]untagged[white]")
 	case "export":
@@ -230,20 +223,14 @@ func (s *Search) HandleCommand(command string) {
 
 		log.Debugf("exporting %s", backupFilePath)
 
-		content := ""
-		for i := 0; i < s.ResultList.GetRowCount(); i++ {
-			log.Debugf("current row %d", s.CurrentRowIndex)
-			doc, err := s.LoadMiniDocFromDB(i)
-			if err != nil {
-				log.Errorf("minidoc from failed: %v", err)
-				return
-			}
-
-			if !doc.IsSelected() {
-				log.Debugf("row %d not selected skipping", i)
-				continue
-			}
+		docs, err := s.SelectedDocs()
+		if err != nil {
+			s.App.SetStatus("[black:red]exporting content: " + err.Error() + "[white]")
+			return
+		}
 
+		content := ""
+		for _, doc := range docs {
 			jsonBytes, err := json.Marshal(doc)
 			if err != nil {
 				log.Errorf("marshalling doc: %v", err)
@@ -265,6 +252,46 @@ func (s *Search) HandleCommand(command string) {
 			s.App.SetStatus("[black:red]opening exported: " + err.Error() + "[white]")
 			return
 		}
+	case "save":
+		s.SaveSearch(strings.Join(terms[1:], " "))
+	case "unsave":
+		s.UnsaveSearch(strings.Join(terms[1:], " "))
+	case "collection":
+		s.ShowCollection(strings.Join(terms[1:], " "))
+	case "collection-save":
+		s.SaveCollection(strings.Join(terms[1:], " "))
+	case "collection-delete":
+		s.DeleteCollection(strings.Join(terms[1:], " "))
+	case "tag-rename":
+		if len(terms) != 3 {
+			s.App.SetStatus("[black:red]usage: @tag-rename old new[white]")
+			return
+		}
+		s.RenameTags(map[string]string{terms[1]: terms[2]})
+	case "tag-merge":
+		into := indexOf(terms, "into")
+		if into < 2 || into != len(terms)-2 {
+			s.App.SetStatus("[black:red]usage: @tag-merge a b into c[white]")
+			return
+		}
+		renames := map[string]string{}
+		for _, tag := range terms[1:into] {
+			renames[tag] = terms[len(terms)-1]
+		}
+		s.RenameTags(renames)
+	case "history":
+		if s.ResultList.GetRowCount() == 0 {
+			s.App.SetStatus("[black:red]no doc selected for history[white]")
+			return
+		}
+		doc, err := s.LoadMiniDocFromDB(s.CurrentRowIndex)
+		if err != nil {
+			log.Errorf("minidoc from failed: %v", err)
+			return
+		}
+		s.ShowHistory(doc)
+	case "trash":
+		s.ShowTrash()
 	case "import":
 		str := terms[1]
 		isWeb := strings.HasPrefix(str, "http")
@@ -284,6 +311,26 @@ func (s *Search) HandleCommand(command string) {
 	}
 }
 
+// RenameTags rewrites tags across every doc and reports how many changed
+func (s *Search) RenameTags(renames map[string]string) {
+	changed, err := s.App.DataHandler.RenameTags(renames)
+	if err != nil {
+		log.Errorf("renaming tags: %v", err)
+		s.App.SetStatus("[black:red]renaming tags: " + err.Error() + "[white]")
+		return
+	}
+	s.App.SetStatus(fmt.Sprintf("[white:darkcyan] %d docs retagged[white]", changed))
+}
+
+func indexOf(terms []string, term string) int {
+	for i, t := range terms {
+		if t == term {
+			return i
+		}
+	}
+	return -1
+}
+
 func ImportFromWeb(str string, s *Search) bool {
 	data, err := HTTPGet(str)
 	if err != nil {
@@ -291,11 +338,13 @@ func ImportFromWeb(str string, s *Search) bool {
 		return true
 	}
 	content := string(data)
+	docs := []MiniDoc{}
 	for _, line := range strings.Split(content, "\n") {
-		errored := ImportLineByLine(line, s)
+		doc, errored := ImportLineByLine(line, s)
 		if errored {
 			continue
 		}
+		docs = append(docs, doc)
 	}
 
 	content = strings.TrimSpace(content)
@@ -303,7 +352,7 @@ func ImportFromWeb(str string, s *Search) bool {
 		s.App.SetStatus(fmt.Sprintf("[black:red]downloaded content empty: %v[white]", err))
 		return true
 	}
-	return false
+	return WriteImported(docs, s)
 }
 
 func ImportFile(str string, s *Search) bool {
@@ -334,18 +383,21 @@ func ImportFile(str string, s *Search) bool {
 	}
 	defer file.Close()
 
+	docs := []MiniDoc{}
 	scanner := bufio.NewScanner(file)
 	for scanner.Scan() {
 		line := scanner.Text()
-		errored := ImportLineByLine(line, s)
+		doc, errored := ImportLineByLine(line, s)
 		if errored {
 			return errored
 		}
+		docs = append(docs, doc)
 	}
-	return false
+	return WriteImported(docs, s)
 }
 
-func ImportLineByLine(line string, s *Search) bool {
+// ImportLineByLine parses a single exported json line into a minidoc ready to be written
+func ImportLineByLine(line string, s *Search) (MiniDoc, bool) {
 	var err error
 	line = strings.TrimSpace(line)
 
@@ -354,18 +406,28 @@ func ImportLineByLine(line string, s *Search) bool {
 	if err != nil {
 		log.Errorf("unmarshaling json=%v", jsonMap)
 		s.App.SetStatus(fmt.Sprintf("[black:red]unmarshaling: %v[white]", err))
-		return true
+		return nil, true
 	}
 
 	doc, err := MiniDocFrom(jsonMap)
 	if err != nil {
 		log.Errorf("minidoc from doc=%v", doc)
 		s.App.SetStatus(fmt.Sprintf("[black:red]minidoc from: %v[white]", err))
-		return true
+		return nil, true
 	}
 	// set the id to 0 so new sequence will be generated
 	doc.SetID(0)
-	s.App.DataHandler.Write(doc)
+	return doc, false
+}
+
+// WriteImported writes all imported docs or none of them
+func WriteImported(docs []MiniDoc, s *Search) bool {
+	err := s.App.DataHandler.WriteAll(docs)
+	if err != nil {
+		log.Errorf("writing imported docs: %v", err)
+		s.App.SetStatus(fmt.Sprintf("[black:red]importing: %v[white]", err))
+		return true
+	}
 	return false
 }
 
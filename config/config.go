@@ -27,6 +27,9 @@ type Provider interface {
 	GetTime(key string) time.Time
 	InConfig(key string) bool
 	IsSet(key string) bool
+	Set(key string, value interface{})
+	UnmarshalKey(key string, rawVal interface{}, opts ...viper.DecoderConfigOption) error
+	WriteConfig() error
 }
 
 var defaultConfig *viper.Viper
@@ -54,6 +57,22 @@ func readViperConfig(appName string) *viper.Viper {
 	v.SetDefault("loglevel", "info")
 	v.SetDefault("log_filename", "minidoc.log")
 	v.SetDefault("generated_doc_path", "/Documents/minidocs")
+	// days a deleted doc stays in the trash, 0 keeps it until purged by hand
+	v.SetDefault("trash_purge_days", 30)
+	// search hits shown per page in the result list
+	v.SetDefault("search_page_size", 100)
+	// pause in typing before the search bar query runs, 0 only searches on Enter
+	v.SetDefault("search_as_you_type_delay_ms", 250)
+	// searches finding fewer docs than this add prefix and fuzzy matches, 0 keeps searches exact
+	v.SetDefault("search_fuzzy_min_hits", 3)
+	// order of search results, one of score, created, updated, title or type, s in the result list picks the next one and saves it here
+	v.SetDefault("search_sort", "score")
+	// extra result list columns shown before the fragments, any of created, updated and tags
+	v.SetDefault("search_columns", []string{})
+	// similar docs listed by m in the preview, 0 turns it off
+	v.SetDefault("related_docs_count", 5)
+	// list open todos past their due day in a desktop notification on startup
+	v.SetDefault("notify_overdue_todos", true)
 
 	// Find home directory.
 	home, err := homedir.Dir()
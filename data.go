@@ -1,23 +1,224 @@
 package minidoc
 
+import (
+	"strings"
+	"time"
+)
+
 type DataHandler struct {
-	BucketHandler *BucketHandler
-	IndexHandler  *IndexHandler
+	Store   Store
+	Indexer Indexer
 }
 
+// Write stores doc and journals it as pending before indexing, the journal entry is cleared once indexing succeeds
 func (dh *DataHandler) Write(doc MiniDoc) (uint32, error) {
-	id, err := dh.BucketHandler.Write(doc)
+	var id uint32
+	err := dh.Store.Update(func(tx *BucketTx) error {
+		var err error
+		id, err = tx.Write(doc)
+		if err != nil {
+			return err
+		}
+		return tx.MarkPending(doc, pendingOpIndex)
+	})
 	if err != nil {
 		return 0, err
 	}
-	err = dh.IndexHandler.Index(doc)
-	return id, err
+	err = dh.Indexer.Index(doc)
+	if err != nil {
+		return id, err
+	}
+	return id, dh.clearPending([]MiniDoc{doc})
+}
+
+// WriteAll writes docs in a single transaction, if any write fails none of the docs are stored
+func (dh *DataHandler) WriteAll(docs []MiniDoc) error {
+	err := dh.Store.Update(func(tx *BucketTx) error {
+		for _, doc := range docs {
+			if _, err := tx.Write(doc); err != nil {
+				return err
+			}
+			if err := tx.MarkPending(doc, pendingOpIndex); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	err = dh.Indexer.IndexAll(docs)
+	if err != nil {
+		return err
+	}
+	return dh.clearPending(docs)
 }
 
+// Delete moves doc to the trash and removes it from the index
 func (dh *DataHandler) Delete(doc MiniDoc) error {
-	err := dh.BucketHandler.Delete(doc)
+	err := dh.Store.Update(func(tx *BucketTx) error {
+		if err := tx.Trash(doc); err != nil {
+			return err
+		}
+		return tx.MarkPending(doc, pendingOpDelete)
+	})
+	if err != nil {
+		return err
+	}
+	err = dh.Indexer.Delete(doc)
+	if err != nil {
+		return err
+	}
+	return dh.clearPending([]MiniDoc{doc})
+}
+
+// DeleteAll moves docs to the trash in a single transaction, if any move fails none of the docs are removed
+func (dh *DataHandler) DeleteAll(docs []MiniDoc) error {
+	err := dh.Store.Update(func(tx *BucketTx) error {
+		for _, doc := range docs {
+			if err := tx.Trash(doc); err != nil {
+				return err
+			}
+			if err := tx.MarkPending(doc, pendingOpDelete); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	err = dh.Indexer.DeleteAll(docs)
+	if err != nil {
+		return err
+	}
+	return dh.clearPending(docs)
+}
+
+// Restore moves a trashed doc back into the store and the index
+func (dh *DataHandler) Restore(doc MiniDoc) error {
+	var restored MiniDoc
+	err := dh.Store.Update(func(tx *BucketTx) error {
+		var err error
+		restored, err = tx.Restore(doc.GetIDString())
+		if err != nil {
+			return err
+		}
+		return tx.MarkPending(restored, pendingOpIndex)
+	})
+	if err != nil {
+		return err
+	}
+	err = dh.Indexer.Index(restored)
+	if err != nil {
+		return err
+	}
+	return dh.clearPending([]MiniDoc{restored})
+}
+
+// Purge removes the doc trashed under docid for good
+func (dh *DataHandler) Purge(docid string) error {
+	return dh.Store.Update(func(tx *BucketTx) error {
+		return tx.Purge(docid)
+	})
+}
+
+// PurgeTrash removes every doc that has been in the trash longer than maxAge
+func (dh *DataHandler) PurgeTrash(maxAge time.Duration) (int, error) {
+	purged := 0
+	cutoff := time.Now().Add(-maxAge)
+	err := dh.Store.Update(func(tx *BucketTx) error {
+		items, err := tx.TrashItems()
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			deleted, err := time.ParseInLocation(dateTimeFormat, item.DeletedDate, time.Local)
+			if err != nil {
+				log.Errorf("invalid deleted date %s for %s: %v", item.DeletedDate, item.DocID, err)
+				continue
+			}
+			if deleted.After(cutoff) {
+				continue
+			}
+			if err := tx.Purge(item.DocID); err != nil {
+				return err
+			}
+			purged++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return purged, nil
+}
+
+func (dh *DataHandler) clearPending(docs []MiniDoc) error {
+	docids := make([]string, len(docs))
+	for i, doc := range docs {
+		docids[i] = doc.GetIDString()
+	}
+	return dh.Store.Update(func(tx *BucketTx) error {
+		return tx.ClearPending(docids...)
+	})
+}
+
+// RecoverPending brings the index in line with the store for every doc left in the journal,
+// docs still in the store are re-indexed and the rest are deleted from the index
+func (dh *DataHandler) RecoverPending() (int, error) {
+	var pending map[string]string
+	err := dh.Store.View(func(tx *BucketTx) error {
+		var err error
+		pending, err = tx.Pending()
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	recovered := 0
+	for docid, op := range pending {
+		log.Debugf("recovering pending %s for %s", op, docid)
+		sslice := strings.Split(docid, ":")
+		if len(sslice) != 2 {
+			log.Errorf("invalid pending doc id %s", docid)
+			continue
+		}
+		doctype := sslice[0]
+		id := toUnit32FromString(sslice[1])
+
+		doc, err := dh.Store.Read(id, doctype)
+		if err == nil {
+			err = dh.Indexer.Index(doc)
+		} else {
+			doc, err = NewDoc(doctype)
+			if err != nil {
+				log.Errorf("instantiating %s", doctype)
+				continue
+			}
+			doc.SetID(id)
+			err = dh.Indexer.Delete(doc)
+		}
+		if err != nil {
+			log.Errorf("recovering %s failed: %v", docid, err)
+			return recovered, err
+		}
+
+		err = dh.clearPending([]MiniDoc{doc})
+		if err != nil {
+			return recovered, err
+		}
+		recovered++
+	}
+
+	return recovered, nil
+}
+
+func (dh *DataHandler) Close() error {
+	err := dh.Store.Close()
 	if err != nil {
 		return err
 	}
-	return dh.IndexHandler.Delete(doc)
+	return dh.Indexer.Close()
 }
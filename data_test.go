@@ -0,0 +1,162 @@
+package minidoc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDataHandler_RecoverPending(t *testing.T) {
+	db := NewMemStore()
+	indexer := NewIndexHandler(WithIndexHandlerInMemory())
+	dh := &DataHandler{db, indexer}
+	defer dh.Close()
+
+	// simulate a crash between the store write and indexing
+	doc := GetTestNoteMiniDoc()
+	doc.Note = "journaled qux"
+	err := db.Update(func(tx *BucketTx) error {
+		if _, err := tx.Write(doc); err != nil {
+			return err
+		}
+		return tx.MarkPending(doc, pendingOpIndex)
+	})
+	if err != nil {
+		t.Log(err)
+		t.Fail()
+	}
+
+	recovered, err := dh.RecoverPending()
+	if err != nil || recovered != 1 {
+		t.Logf("recovered %d: %v", recovered, err)
+		t.Fail()
+	}
+
+	result, _ := indexer.Search("qux", 0, 0)
+	if len(result.Docs) == 0 {
+		t.Log("recovered doc should be searchable")
+		t.Fail()
+	}
+
+	recovered, _ = dh.RecoverPending()
+	if recovered != 0 {
+		t.Log("journal should be empty after recovery")
+		t.Fail()
+	}
+}
+
+func TestDataHandler_WriteAll_InMemory(t *testing.T) {
+	dh := NewTestDataHandler()
+	defer dh.Close()
+
+	docs := []MiniDoc{GetTestUrlMiniDoc(), GetTestNoteMiniDoc(), GetTestTodoMiniDoc()}
+	if err := dh.WriteAll(docs); err != nil {
+		t.Log(err)
+		t.Fail()
+	}
+
+	result, _ := dh.Indexer.Search("baz", 0, 0)
+	if len(result.Docs) != len(docs) {
+		t.Logf("expected %d docs but found %d", len(docs), len(result.Docs))
+		t.Fail()
+	}
+
+	if err := dh.DeleteAll(docs); err != nil {
+		t.Log(err)
+		t.Fail()
+	}
+
+	result, _ = dh.Indexer.Search("baz", 0, 0)
+	if len(result.Docs) != 0 {
+		t.Log("deleted docs should not be searchable")
+		t.Fail()
+	}
+}
+
+// NewTestDataHandler returns a data handler that never touches the filesystem
+func NewTestDataHandler() *DataHandler {
+	return &DataHandler{
+		Store:   NewMemStore(),
+		Indexer: NewIndexHandler(WithIndexHandlerInMemory()),
+	}
+}
+
+func TestDataHandler_Delete_Trash(t *testing.T) {
+	dh := NewTestDataHandler()
+	defer dh.Close()
+
+	doc := GetTestNoteMiniDoc()
+	doc.Note = "trashed quux"
+	ID, _ := dh.Write(doc)
+
+	if err := dh.Delete(doc); err != nil {
+		t.Log(err)
+		t.Fail()
+	}
+	if _, err := dh.Store.Read(ID, doc.GetType()); err == nil {
+		t.Log("trashed doc should not be readable")
+		t.Fail()
+	}
+	if result, _ := dh.Indexer.Search("quux", 0, 0); len(result.Docs) != 0 {
+		t.Log("trashed doc should not be searchable")
+		t.Fail()
+	}
+
+	if err := dh.Restore(doc); err != nil {
+		t.Log(err)
+		t.Fail()
+	}
+	if _, err := dh.Store.Read(ID, doc.GetType()); err != nil {
+		t.Log("restored doc should be readable")
+		t.Fail()
+	}
+	if result, _ := dh.Indexer.Search("quux", 0, 0); len(result.Docs) != 1 {
+		t.Log("restored doc should be searchable")
+		t.Fail()
+	}
+}
+
+func TestDataHandler_PurgeTrash(t *testing.T) {
+	dh := NewTestDataHandler()
+	defer dh.Close()
+
+	doc := GetTestNoteMiniDoc()
+	dh.Write(doc)
+	dh.Delete(doc)
+
+	// a doc of a doctype no longer registered
+	record := `{"deleted_date":"` + time.Now().Format(dateTimeFormat) + `","doc":{"id":1,"type":"removed","title":"gone"}}`
+	err := dh.Store.Update(func(tx *BucketTx) error {
+		return tx.kv.Put(trashBucketName, []byte("removed:1"), []byte(record))
+	})
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	var items []TrashItem
+	dh.Store.View(func(tx *BucketTx) error {
+		items, err = tx.TrashItems()
+		return err
+	})
+	if len(items) != 2 || items[1].DocID != "removed:1" || items[1].Err == nil || items[1].Doc.GetTitle() != "gone" {
+		t.Logf("expected the undecodable doc listed with its error but got %v: %v", items, err)
+		t.Fail()
+	}
+
+	purged, _ := dh.PurgeTrash(time.Hour)
+	if purged != 0 {
+		t.Log("recently trashed doc should be kept")
+		t.Fail()
+	}
+
+	purged, err = dh.PurgeTrash(-time.Hour)
+	if purged != 2 || err != nil {
+		t.Logf("expired docs should be purged, the one of an unknown doctype too, but got %d: %v", purged, err)
+		t.Fail()
+	}
+
+	if err := dh.Restore(doc); err == nil {
+		t.Log("purged doc should not be restorable")
+		t.Fail()
+	}
+}
@@ -1,19 +1,43 @@
 package minidoc
 
 import (
+	"bytes"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
-	"github.com/joyrexus/buckets"
+	"github.com/boltdb/bolt"
 	"strconv"
 	"time"
 )
 
 const defaultDbFileName = ".minidoc/store.db"
 
+const sequenceBucketName = "_sequence"
+
+const dateTimeFormat = "2006-01-02 15:04:05"
+
+// pendingIndexBucketName journals docs whose index update has not completed yet
+const pendingIndexBucketName = "_pending_index"
+
+const (
+	pendingOpIndex  = "index"
+	pendingOpDelete = "delete"
+)
+
+// Store persists minidocs by doctype and id
+type Store interface {
+	Write(doc MiniDoc) (uint32, error)
+	Read(key uint32, doctype string) (MiniDoc, error)
+	ReadAll(doctype string) ([]MiniDoc, error)
+	Delete(doc MiniDoc) error
+	Update(fn func(tx *BucketTx) error) error
+	View(fn func(tx *BucketTx) error) error
+	Close() error
+}
+
 type BucketHandler struct {
 	debug  func(string)
-	bx     *buckets.DB
+	db     *bolt.DB
 	DBPath string
 }
 
@@ -32,6 +56,7 @@ func WithBucketHandlerDBPath(path string) BucketHandlerOption {
 	}
 }
 
+// NewBucketHandler opens the bolt db and keeps it open until Close is called
 func NewBucketHandler(opts ...BucketHandlerOption) *BucketHandler {
 	bh := &BucketHandler{
 		debug:  func(string) {},
@@ -42,107 +67,259 @@ func NewBucketHandler(opts ...BucketHandlerOption) *BucketHandler {
 		opt(bh)
 	}
 
+	db, err := bolt.Open(bh.DBPath, 0600, &bolt.Options{Timeout: 3 * time.Second})
+	if err != nil {
+		log.Fatalf("error while opening db at %s: %v", bh.DBPath, err)
+		return nil
+	}
+	bh.db = db
+	log.Debug("db opened successfully")
+
 	return bh
 }
 
+// Close closes the underlying bolt db
+func (bh *BucketHandler) Close() error {
+	return bh.db.Close()
+}
+
+// Backup copies the db file next to itself while the db stays open
+func (bh *BucketHandler) Backup(suffix string) (string, error) {
+	path := bh.DBPath + "." + suffix + ".bak"
+	err := bh.db.View(func(tx *bolt.Tx) error {
+		return tx.CopyFile(path, 0600)
+	})
+	return path, err
+}
+
+// Update runs fn inside a read-write transaction, everything fn does is committed or rolled back together
+func (bh *BucketHandler) Update(fn func(tx *BucketTx) error) error {
+	return bh.db.Update(func(tx *bolt.Tx) error {
+		return fn(NewBucketTx(&boltTx{tx}))
+	})
+}
+
+// View runs fn inside a read-only transaction
+func (bh *BucketHandler) View(fn func(tx *BucketTx) error) error {
+	return bh.db.View(func(tx *bolt.Tx) error {
+		return fn(NewBucketTx(&boltTx{tx}))
+	})
+}
+
 func (bh *BucketHandler) Write(doc MiniDoc) (uint32, error) {
-	bx, err := buckets.Open(bh.DBPath)
-	if err != nil {
-		log.Errorf("error while opening bucket[%s] at %s: %v", doc.GetType(), bh.DBPath, err)
-		return 0, err
+	var id uint32
+	err := bh.Update(func(tx *BucketTx) error {
+		var err error
+		id, err = tx.Write(doc)
+		return err
+	})
+	return id, err
+}
+
+func (bh *BucketHandler) ReadAll(doctype string) ([]MiniDoc, error) {
+	var docs []MiniDoc
+	err := bh.View(func(tx *BucketTx) error {
+		var err error
+		docs, err = tx.ReadAll(doctype)
+		return err
+	})
+	return docs, err
+}
+
+func (bh *BucketHandler) Read(key uint32, doctype string) (MiniDoc, error) {
+	var doc MiniDoc
+	err := bh.View(func(tx *BucketTx) error {
+		var err error
+		doc, err = tx.Read(key, doctype)
+		return err
+	})
+	return doc, err
+}
+
+func (bh *BucketHandler) Delete(doc MiniDoc) error {
+	return bh.Update(func(tx *BucketTx) error {
+		return tx.Delete(doc)
+	})
+}
+
+// BucketTx exposes minidoc operations on a single transaction of the underlying store
+type BucketTx struct {
+	kv KVTx
+}
+
+// NewBucketTx wraps a transaction of a store, a Store outside this package hands it to the Update and View funcs
+func NewBucketTx(kv KVTx) *BucketTx {
+	return &BucketTx{kv}
+}
+
+// KVTx is the set of bucket primitives a store transaction has to provide. Get returns nil for a missing key,
+// ForEach and ForEachPrefix visit keys in byte order and writes to a missing bucket create it
+type KVTx interface {
+	Get(bucket string, key []byte) []byte
+	Put(bucket string, key, value []byte) error
+	Delete(bucket string, key []byte) error
+	ForEach(bucket string, fn func(k, v []byte) error) error
+	ForEachPrefix(bucket string, prefix []byte, fn func(k, v []byte) error) error
+	// LastWithPrefix returns the last key starting with prefix and its value, nil when there is none
+	LastWithPrefix(bucket string, prefix []byte) (k, v []byte)
+}
+
+type boltTx struct {
+	tx *bolt.Tx
+}
+
+func (b *boltTx) Get(bucket string, key []byte) []byte {
+	bk := b.tx.Bucket([]byte(bucket))
+	if bk == nil {
+		return nil
 	}
-	defer bx.Close()
-	doctype := doc.GetType()
+	return bk.Get(key)
+}
 
-	// Create a new bucket
-	bucket, err := bx.New([]byte(doctype))
+func (b *boltTx) Put(bucket string, key, value []byte) error {
+	bk, err := b.tx.CreateBucketIfNotExists([]byte(bucket))
 	if err != nil {
-		log.Errorf("error while opening or creating bucket[%s]: %v", doctype, err)
-		return 0, err
+		log.Errorf("error while opening or creating bucket[%s]: %v", bucket, err)
+		return err
 	}
+	return bk.Put(key, value)
+}
+
+func (b *boltTx) Delete(bucket string, key []byte) error {
+	bk := b.tx.Bucket([]byte(bucket))
+	if bk == nil {
+		return nil
+	}
+	return bk.Delete(key)
+}
 
+func (b *boltTx) ForEach(bucket string, fn func(k, v []byte) error) error {
+	bk := b.tx.Bucket([]byte(bucket))
+	if bk == nil {
+		return nil
+	}
+	return bk.ForEach(fn)
+}
+
+func (b *boltTx) ForEachPrefix(bucket string, prefix []byte, fn func(k, v []byte) error) error {
+	bk := b.tx.Bucket([]byte(bucket))
+	if bk == nil {
+		return nil
+	}
+	c := bk.Cursor()
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		if err := fn(k, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *boltTx) LastWithPrefix(bucket string, prefix []byte) ([]byte, []byte) {
+	bk := b.tx.Bucket([]byte(bucket))
+	if bk == nil {
+		return nil, nil
+	}
+	c := bk.Cursor()
+	// seek past every key under prefix and step back
+	k, v := c.Seek(append(append([]byte{}, prefix...), 0xff))
+	if k == nil {
+		k, v = c.Last()
+	} else {
+		k, v = c.Prev()
+	}
+	if k == nil || !bytes.HasPrefix(k, prefix) {
+		return nil, nil
+	}
+	return k, v
+}
+
+func (tx *BucketTx) Write(doc MiniDoc) (uint32, error) {
+	doctype := doc.GetType()
+
+	var err error
 	key := toBytes(doc.GetID())
 	if doc.GetID() == 0 {
 		log.Debugf("id == 0 doctype [%s] generating new sequence", doctype)
 
-		key, err = NextSequence(bx, doctype)
+		key, err = NextSequence(tx, doctype)
 		if err != nil {
 			log.Errorf("error while next sequence doctype [%s]: %v", doctype, err)
+			return 0, err
 		}
 		doc.SetID(toUint32(key))
 	}
-	nowstr := time.Now().Format("2006-01-02 15:04:05")
-	doc.SetCreatedDate(nowstr)
+	nowstr := time.Now().Format(dateTimeFormat)
+	doc.SetCreatedDate(tx.createdDate(doctype, key, doc, nowstr))
+	doc.SetUpdatedDate(nowstr)
 
-	data, err := json.Marshal(doc.GetJSON())
+	jsonMap := doc.GetJSON()
+	if record, ok := jsonMap.(map[string]interface{}); ok {
+		record[schemaVersionField] = CurrentSchemaVersion()
+	}
+	data, err := json.Marshal(jsonMap)
 	if err != nil {
 		log.Errorf("error while marshalling: %v", err)
 		return 0, err
 	}
-	err = bucket.Put(key, data)
+
+	err = tx.keepRevision(doctype, doc.GetID(), data)
+	if err != nil {
+		log.Errorf("error while keeping revision of %s: %v", doc.GetIDString(), err)
+		return 0, err
+	}
+
+	err = tx.kv.Put(doctype, key, data)
 	if err != nil {
 		log.Errorf("error while bucket put: %v", err)
+		return 0, err
 	}
 
 	return toUint32(key), nil
 }
 
-func (bh *BucketHandler) ReadAll(doctype string) ([]MiniDoc, error) {
-	bx, err := buckets.Open(bh.DBPath)
-	if err != nil {
-		log.Errorf("error while opening bucket[%s] at %s: %v", doctype, bh.DBPath, err)
-	}
-	defer bx.Close()
-
-	bucket, err := bx.New([]byte(doctype))
-	if err != nil {
-		log.Errorf("error while opening or creating bucket[%s]: %v", doctype, err)
-		return nil, err
+// createdDate keeps the created date of an already stored doc so it never changes after the first write
+func (tx *BucketTx) createdDate(doctype string, key []byte, doc MiniDoc, now string) string {
+	if prev := tx.kv.Get(doctype, key); prev != nil {
+		var stored BaseDoc
+		if err := json.Unmarshal(prev, &stored); err == nil && len(stored.CreatedDate) > 0 {
+			return stored.CreatedDate
+		}
 	}
-
-	items, err := bucket.Items()
-	if err != nil {
-		log.Errorf("error while iterating all items in bucket[%s]: %v", doctype, err)
-		return nil, err
+	// e.g. imported docs bring their own created date
+	if len(doc.GetCreatedDate()) > 0 {
+		return doc.GetCreatedDate()
 	}
+	return now
+}
 
-	docs := make([]MiniDoc, len(items))
-	for i, item := range items {
+func (tx *BucketTx) ReadAll(doctype string) ([]MiniDoc, error) {
+	docs := []MiniDoc{}
+	err := tx.kv.ForEach(doctype, func(k, v []byte) error {
 		doc, err := NewDoc(doctype)
 		if err != nil {
 			log.Errorf("instantiating %s", doctype)
-			return nil, err
+			return err
 		}
 
-		err = json.Unmarshal(item.Value, doc)
+		err = json.Unmarshal(v, doc)
 		if err != nil {
 			log.Errorf("error while unmarshalling %s: %v", doctype, err)
-			return nil, err
+			return err
 		}
-		docs[i] = doc
+		docs = append(docs, doc)
+		return nil
+	})
+	if err != nil {
+		log.Errorf("error while iterating all items in bucket[%s]: %v", doctype, err)
+		return nil, err
 	}
 
 	return docs, nil
 }
 
-func (bh *BucketHandler) Read(key uint32, doctype string) (MiniDoc, error) {
-	bx, err := buckets.Open(bh.DBPath)
-	if err != nil {
-		log.Errorf("error while opening bucket[%s] at %s: %v", doctype, bh.DBPath, err)
-	}
-	defer bx.Close()
-
-	bucket, err := bx.New([]byte(doctype))
-	if err != nil {
-		log.Errorf("error while opening or creating bucket[%s]: %v", doctype, err)
-		return nil, err
-	}
-
-	data, err := bucket.Get(toBytes(key))
-	if err != nil {
-		log.Errorf("error while getting item in bucket[%s] with key[%d]: %v", doctype, key, err)
-		return nil, err
-	}
+func (tx *BucketTx) Read(key uint32, doctype string) (MiniDoc, error) {
+	data := tx.kv.Get(doctype, toBytes(key))
 
 	if data == nil {
 		msg := fmt.Sprintf("item not found in bucket[%s] with key[%d]", doctype, key)
@@ -165,24 +342,12 @@ func (bh *BucketHandler) Read(key uint32, doctype string) (MiniDoc, error) {
 	return doc, nil
 }
 
-func (bh *BucketHandler) Delete(doc MiniDoc) error {
+func (tx *BucketTx) Delete(doc MiniDoc) error {
 	key := toBytes(doc.GetID())
 	doctype := doc.GetType()
 
-	bx, err := buckets.Open(bh.DBPath)
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer bx.Close()
-
-	bucket, err := bx.New([]byte(doctype))
-	if err != nil {
-		log.Errorf("error while opening or creating bucket[%s]: %v", doctype, err)
-		return err
-	}
-
 	log.Debugf("deleting in bucket[%s] with key[%d]", doctype, key)
-	err = bucket.Delete(key)
+	err := tx.kv.Delete(doctype, key)
 	if err != nil {
 		log.Errorf("deleting in bucket[%s] with key[%d]: %v", doctype, key, err)
 		return err
@@ -191,33 +356,45 @@ func (bh *BucketHandler) Delete(doc MiniDoc) error {
 	return nil
 }
 
-// NextSequence returns next sequence
-func NextSequence(bx *buckets.DB, sequenceName string) ([]byte, error) {
-	// get or create sequence bucket
-	bucket, err := bx.New([]byte("_sequence"))
-	if err != nil {
-		log.Errorf("error while opening bucket for _sequence for doctype[%s]: %v", sequenceName, err)
-		return nil, err
+// MarkPending records that doc still needs op applied to the index
+func (tx *BucketTx) MarkPending(doc MiniDoc, op string) error {
+	return tx.kv.Put(pendingIndexBucketName, []byte(doc.GetIDString()), []byte(op))
+}
+
+// ClearPending removes the journal entries of docs once the index is up to date
+func (tx *BucketTx) ClearPending(docids ...string) error {
+	for _, docid := range docids {
+		if err := tx.kv.Delete(pendingIndexBucketName, []byte(docid)); err != nil {
+			log.Errorf("clearing pending index for %s: %v", docid, err)
+			return err
+		}
 	}
+	return nil
+}
+
+// Pending returns journal entries as doc id string to pending op
+func (tx *BucketTx) Pending() (map[string]string, error) {
+	pending := map[string]string{}
+	err := tx.kv.ForEach(pendingIndexBucketName, func(k, v []byte) error {
+		pending[string(k)] = string(v)
+		return nil
+	})
+	return pending, err
+}
 
+// NextSequence returns next sequence
+func NextSequence(tx *BucketTx, sequenceName string) ([]byte, error) {
 	// get or create next sequence for given bucket
 	key := []byte(sequenceName)
 
-	next, err := bucket.Get(key)
-	if err != nil {
-		log.Errorf("error while retrieving current key for _sequence for doctype[%s]: %v", sequenceName, err)
-		return nil, err
-	}
+	next := tx.kv.Get(sequenceBucketName, key)
 
-	if next == nil || len(next) == 0 {
-		bs := toBytes(1)
-		bucket.Put(key, bs)
-		return bs, nil
+	nextVal := uint32(1)
+	if len(next) > 0 {
+		nextVal = toUint32(next) + 1
 	}
 
-	nextVal := toUint32(next)
-	nextVal++
-	err = bucket.Put(key, toBytes(nextVal))
+	err := tx.kv.Put(sequenceBucketName, key, toBytes(nextVal))
 	if err != nil {
 		log.Errorf("error while putting next key for _sequence for doctype[%s]: %v", sequenceName, err)
 		return nil, err
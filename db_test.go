@@ -12,6 +12,7 @@ func init() {
 
 func TestBucketHandler_Write(t *testing.T) {
 	db := NewBucketHandler()
+	defer db.Close()
 	doc := GetTestUrlMiniDoc()
 	ID, err := db.Write(doc)
 	if err != nil || ID == 0 {
@@ -21,8 +22,9 @@ func TestBucketHandler_Write(t *testing.T) {
 }
 
 func TestBucketHandler_Read(t *testing.T) {
+	db := NewBucketHandler()
+	defer db.Close()
 	for i := 0; i < 0; i++ {
-		db := NewBucketHandler()
 		doc := GetTestUrlMiniDoc()
 		ID, err := db.Write(doc)
 		if err != nil || ID == 0 {
@@ -36,7 +38,6 @@ func TestBucketHandler_Read(t *testing.T) {
 		fmt.Println(doc2)
 	}
 	for i := 0; i < 10; i++ {
-		db := NewBucketHandler()
 		doc := GetTestNoteMiniDoc()
 		ID, err := db.Write(doc)
 		if err != nil || ID == 0 {
@@ -50,7 +51,6 @@ func TestBucketHandler_Read(t *testing.T) {
 		fmt.Println(doc2)
 	}
 	for i := 0; i < 10; i++ {
-		db := NewBucketHandler()
 		doc := GetTestTodoMiniDoc()
 		ID, err := db.Write(doc)
 		if err != nil || ID == 0 {
@@ -67,6 +67,7 @@ func TestBucketHandler_Read(t *testing.T) {
 
 func TestBucketHandler_Delete(t *testing.T) {
 	db := NewBucketHandler()
+	defer db.Close()
 	doc := GetTestNoteMiniDoc()
 	ID, err := db.Write(doc)
 	if err != nil || ID == 0 {
@@ -85,3 +86,102 @@ func TestBucketHandler_Delete(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestBucketHandler_Update_Rollback(t *testing.T) {
+	db := NewBucketHandler()
+	defer db.Close()
+
+	doc := GetTestNoteMiniDoc()
+	err := db.Update(func(tx *BucketTx) error {
+		if _, err := tx.Write(doc); err != nil {
+			return err
+		}
+		return fmt.Errorf("abort")
+	})
+	if err == nil {
+		t.Log("we should get the abort error back")
+		t.Fail()
+	}
+
+	_, err = db.Read(doc.GetID(), doc.GetType())
+	if err == nil {
+		t.Log("write should have been rolled back")
+		t.Fail()
+	}
+}
+
+func TestBucketTx_History(t *testing.T) {
+	db := NewMemStore()
+	doc := GetTestNoteMiniDoc()
+	ID, _ := db.Write(doc)
+
+	// writing the same content again should not add a revision
+	db.Write(doc)
+
+	doc.Note = "my note changed"
+	db.Write(doc)
+
+	var revisions []Revision
+	db.View(func(tx *BucketTx) error {
+		var err error
+		revisions, err = tx.History(ID, doc.GetType())
+		return err
+	})
+
+	if len(revisions) != 1 {
+		t.Logf("expected 1 revision but got %d", len(revisions))
+		t.FailNow()
+	}
+	prev, _ := revisions[0].Doc.(*NoteDoc)
+	if revisions[0].Number != 1 || prev.Note != "my note foo" {
+		t.Logf("unexpected revision %v", revisions[0])
+		t.Fail()
+	}
+}
+
+func TestBucketTx_Write_CreatedDateImmutable(t *testing.T) {
+	db := NewMemStore()
+	doc := GetTestNoteMiniDoc()
+	ID, _ := db.Write(doc)
+	created := doc.GetCreatedDate()
+
+	doc.SetCreatedDate("2001-01-01 00:00:00")
+	doc.Note = "my note changed"
+	db.Write(doc)
+
+	stored, _ := db.Read(ID, doc.GetType())
+	if stored.GetCreatedDate() != created {
+		t.Logf("created date changed from %s to %s", created, stored.GetCreatedDate())
+		t.Fail()
+	}
+	if len(stored.GetUpdatedDate()) == 0 {
+		t.Log("updated date should be set")
+		t.Fail()
+	}
+}
+
+func TestKVTx_LastWithPrefix(t *testing.T) {
+	bolt := NewBucketHandler()
+	defer bolt.Close()
+
+	for name, store := range map[string]Store{"bolt": bolt, "mem": NewMemStore()} {
+		bucket := "_test_last_with_prefix"
+		store.Update(func(tx *BucketTx) error {
+			for _, k := range []string{"1:0000000001", "1:0000000002", "10:0000000001", "2:0000000001"} {
+				tx.kv.Put(bucket, []byte(k), []byte(k))
+			}
+			return nil
+		})
+
+		store.View(func(tx *BucketTx) error {
+			tests := map[string]string{"1:": "1:0000000002", "10:": "10:0000000001", "2:": "2:0000000001", "3:": ""}
+			for prefix, expected := range tests {
+				if k, _ := tx.kv.LastWithPrefix(bucket, []byte(prefix)); string(k) != expected {
+					t.Logf("%s %s: expected %q but got %q", name, prefix, expected, k)
+					t.Fail()
+				}
+			}
+			return nil
+		})
+	}
+}
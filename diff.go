@@ -0,0 +1,58 @@
+package minidoc
+
+const (
+	DiffSame    = ' '
+	DiffAdded   = '+'
+	DiffRemoved = '-'
+)
+
+type DiffLine struct {
+	Op   rune
+	Text string
+}
+
+// LineDiff returns the lines needed to turn a into b based on the longest common subsequence
+func LineDiff(a, b []string) []DiffLine {
+	n, m := len(a), len(b)
+
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	diff := []DiffLine{}
+	i, j := 0, 0
+	for i < n && j < m {
+		switch {
+		case a[i] == b[j]:
+			diff = append(diff, DiffLine{DiffSame, a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, DiffLine{DiffRemoved, a[i]})
+			i++
+		default:
+			diff = append(diff, DiffLine{DiffAdded, b[j]})
+			j++
+		}
+	}
+	for ; i < n; i++ {
+		diff = append(diff, DiffLine{DiffRemoved, a[i]})
+	}
+	for ; j < m; j++ {
+		diff = append(diff, DiffLine{DiffAdded, b[j]})
+	}
+
+	return diff
+}
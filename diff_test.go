@@ -0,0 +1,31 @@
+package minidoc
+
+import (
+	"testing"
+)
+
+func TestLineDiff(t *testing.T) {
+	a := []string{"title: foo", "note:", "one", "two", "tags: a"}
+	b := []string{"title: foo", "note:", "one", "three", "tags: a"}
+
+	diff := LineDiff(a, b)
+	expected := []DiffLine{
+		{DiffSame, "title: foo"},
+		{DiffSame, "note:"},
+		{DiffSame, "one"},
+		{DiffRemoved, "two"},
+		{DiffAdded, "three"},
+		{DiffSame, "tags: a"},
+	}
+
+	if len(diff) != len(expected) {
+		t.Logf("expected %v but got %v", expected, diff)
+		t.FailNow()
+	}
+	for i := range expected {
+		if diff[i] != expected[i] {
+			t.Logf("line %d expected %v but got %v", i, expected[i], diff[i])
+			t.Fail()
+		}
+	}
+}
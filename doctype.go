@@ -0,0 +1,149 @@
+package minidoc
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gdamore/tcell"
+)
+
+// DocType describes a kind of minidoc, everything the app needs to know about it is registered with RegisterDocType
+type DocType struct {
+	// Name goes in the type field of every doc of this type and names the bucket they are kept in
+	Name string
+	// New returns an empty doc, usually a struct embedding BaseDoc with a GetJSON of its own so its fields are kept
+	New func() MiniDoc
+	// Index tells how docs of this type are indexed
+	Index IndexSpec
+	// ToggleField is the bool field t toggles, e.g. done, docs cannot be toggled when it is empty
+	ToggleField string
+	// DisplayFields are shown in the preview, BaseDoc fields are shown when empty
+	DisplayFields []string
+	// EditFields are shown in the edit form, BaseDoc fields are used when empty
+	EditFields []string
+	// ViEditFields are edited in vim
+	ViEditFields []string
+	// Actions lists the keys the doc handles for the status bar, e.g. "o <- open url in browser"
+	Actions string
+	// NewDocKey starts a new doc of this type from anywhere in the app, zero for none
+	NewDocKey tcell.Key
+	// Fields declares the fields of a doctype whose docs are FieldDocs, e.g. one declared in the config file
+	Fields []DocField
+	// TitleField is the field a FieldDoc is titled by
+	TitleField string
+}
+
+// docTypeRegistry holds every registered doctype by name
+var docTypeRegistry = map[string]*DocType{}
+
+// doctypes are the names of the registered doctypes in the order they were registered
+var doctypes = []string{}
+
+// RegisterDocType makes a doctype known to the app, register from an init function or before NewSimpleApp runs
+func RegisterDocType(dt DocType) error {
+	if len(dt.Name) == 0 {
+		return fmt.Errorf("doctype needs a name")
+	}
+	if dt.New == nil {
+		return fmt.Errorf("doctype %s needs a New func", dt.Name)
+	}
+	if _, found := docTypeRegistry[dt.Name]; found {
+		return fmt.Errorf("doctype %s already registered", dt.Name)
+	}
+	for _, key := range appKeys {
+		if dt.NewDocKey == key {
+			return fmt.Errorf("doctype %s: new doc key %s is used by the app", dt.Name, tcell.KeyNames[key])
+		}
+	}
+	if dt.NewDocKey != 0 {
+		if existing, found := docTypeForKey(dt.NewDocKey); found {
+			return fmt.Errorf("doctype %s: new doc key already used by %s", dt.Name, existing.Name)
+		}
+	}
+
+	// the toggle field is searched as true or false like any other bool field
+	if len(dt.ToggleField) > 0 && !contains(dt.Index.Bool, dt.ToggleField) {
+		dt.Index.Bool = append(append([]string{}, dt.Index.Bool...), dt.ToggleField)
+	}
+
+	docTypeRegistry[dt.Name] = &dt
+	doctypes = append(doctypes, dt.Name)
+	return nil
+}
+
+func mustRegisterDocType(dt DocType) {
+	if err := RegisterDocType(dt); err != nil {
+		panic(err)
+	}
+}
+
+// docTypeNamed returns the registered doctype called name
+func docTypeNamed(name string) (*DocType, bool) {
+	dt, found := docTypeRegistry[name]
+	return dt, found
+}
+
+// docTypeForKey returns the doctype a new doc is started for by key
+func docTypeForKey(key tcell.Key) (*DocType, bool) {
+	for _, name := range doctypes {
+		if dt := docTypeRegistry[name]; dt.NewDocKey == key {
+			return dt, true
+		}
+	}
+	return nil, false
+}
+
+// indexSpecOf tells how docs of doctype are indexed, unknown doctypes have an empty spec
+func indexSpecOf(doctype string) IndexSpec {
+	if dt, found := docTypeNamed(doctype); found {
+		return dt.Index
+	}
+	return IndexSpec{}
+}
+
+// IsToggled reads the toggle field of the doctype of doc from its json, docs without one are never toggled
+func IsToggled(doc MiniDoc) bool {
+	dt, found := docTypeNamed(doc.GetType())
+	if !found || len(dt.ToggleField) == 0 {
+		return false
+	}
+	jsonMap, _ := JsonMapFrom(doc).(map[string]interface{})
+	toggled, _ := jsonMap[dt.ToggleField].(bool)
+	return toggled
+}
+
+// toggleMark shows whether doc is toggled in the result list
+func toggleMark(doc MiniDoc) string {
+	if IsToggled(doc) {
+		return "✓️"
+	}
+	return " "
+}
+
+// ToggleDoc sets the toggle field of doc to toggle. A doctype whose SetToggle changes the doc, e.g. a recurring
+// todo moving to its next due day, keeps that behavior, any other doc has its toggle field set through its json.
+func ToggleDoc(doc MiniDoc, toggle bool) (MiniDoc, error) {
+	dt, found := docTypeNamed(doc.GetType())
+	if !found || len(dt.ToggleField) == 0 {
+		return doc, fmt.Errorf("%s cannot be toggled", doc.GetIDString())
+	}
+
+	before, err := json.Marshal(doc.GetJSON())
+	if err != nil {
+		return doc, err
+	}
+	doc.SetToggle(toggle)
+	after, err := json.Marshal(doc.GetJSON())
+	if err != nil {
+		return doc, err
+	}
+	if string(before) != string(after) {
+		return doc, nil
+	}
+
+	jsonMap, ok := JsonMapFrom(doc).(map[string]interface{})
+	if !ok {
+		return doc, fmt.Errorf("%s has no json fields", doc.GetIDString())
+	}
+	jsonMap[dt.ToggleField] = toggle
+	return MiniDocFrom(jsonMap)
+}
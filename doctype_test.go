@@ -0,0 +1,88 @@
+package minidoc
+
+import (
+	"github.com/gdamore/tcell"
+	"testing"
+)
+
+type bookmarkDoc struct {
+	BaseDoc
+	Page int  `json:"page"`
+	Read bool `json:"read"`
+}
+
+func (d *bookmarkDoc) GetJSON() interface{} {
+	return JsonMapFrom(d)
+}
+
+func TestRegisterDocType(t *testing.T) {
+	registered := append([]string{}, doctypes...)
+	defer func() {
+		delete(docTypeRegistry, "bookmark")
+		doctypes = registered
+	}()
+
+	err := RegisterDocType(DocType{
+		Name:          "bookmark",
+		New:           func() MiniDoc { return &bookmarkDoc{} },
+		Index:         IndexSpec{Text: []string{"title"}, SortTitle: "title"},
+		ToggleField:   "read",
+		DisplayFields: []string{"id", "type", "title", "page", "read"},
+		NewDocKey:     tcell.KeyCtrlB,
+	})
+	if err != nil {
+		t.Logf("registering bookmark: %v", err)
+		t.FailNow()
+	}
+
+	doc, err := NewDoc("bookmark")
+	if err != nil || doc.GetType() != "bookmark" || !doc.IsTogglable() || len(doc.GetDisplayFields()) != 5 {
+		t.Logf("unexpected bookmark doc %v: %v", doc, err)
+		t.Fail()
+	}
+	if dt, found := docTypeForKey(tcell.KeyCtrlB); !found || dt.Name != "bookmark" {
+		t.Log("expected Ctrl-b to start a bookmark")
+		t.Fail()
+	}
+
+	db := NewMemStore()
+	bookmark := &bookmarkDoc{BaseDoc: BaseDoc{Type: "bookmark", Title: "go spec"}, Page: 12}
+	id, err := db.Write(bookmark)
+	if err != nil {
+		t.Logf("writing bookmark: %v", err)
+		t.FailNow()
+	}
+	stored, _ := db.Read(id, "bookmark")
+	toggled, err := ToggleDoc(stored, !IsToggled(stored))
+	if err != nil {
+		t.Logf("toggling bookmark: %v", err)
+		t.FailNow()
+	}
+	db.Write(toggled)
+	reread, _ := db.Read(id, "bookmark")
+	if read, ok := reread.(*bookmarkDoc); !ok || !read.Read || read.Page != 12 || !IsToggled(reread) {
+		t.Logf("expected the bookmark read after toggling but got %+v", reread)
+		t.Fail()
+	}
+
+	if _, err := ParseQuery("read:false"); err != nil {
+		t.Logf("expected the toggle field to be searchable: %v", err)
+		t.Fail()
+	}
+
+	failing := map[string]DocType{
+		"duplicate name": {Name: "note", New: func() MiniDoc { return &NoteDoc{} }},
+		"duplicate key":  {Name: "quote", New: func() MiniDoc { return &NoteDoc{} }, NewDocKey: tcell.KeyCtrlN},
+		"missing New":    {Name: "quote"},
+		"missing name":   {New: func() MiniDoc { return &NoteDoc{} }},
+		"app key":        {Name: "quote", New: func() MiniDoc { return &NoteDoc{} }, NewDocKey: tcell.KeyCtrlL},
+		"tab key":        {Name: "quote", New: func() MiniDoc { return &NoteDoc{} }, NewDocKey: tcell.KeyCtrlI},
+		"enter key":      {Name: "quote", New: func() MiniDoc { return &NoteDoc{} }, NewDocKey: tcell.KeyCtrlM},
+	}
+	for name, dt := range failing {
+		if err := RegisterDocType(dt); err == nil {
+			t.Logf("%s: expected registering to fail", name)
+			t.Fail()
+		}
+	}
+}
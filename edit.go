@@ -1,6 +1,7 @@
 package minidoc
 
 import (
+	"fmt"
 	"github.com/rivo/tview"
 	"strconv"
 	"strings"
@@ -60,9 +61,22 @@ mainLoop:
 		label := cleanfieldname + ":"
 		switch fieldtype {
 		case "string":
+			if field, ok := docFieldOf(doc.GetType(), fieldname); ok && field.Type == fieldTypeEnum {
+				value := j.string(fieldname)
+				selected := 0
+				for i, v := range field.Values {
+					if v == value {
+						selected = i
+					}
+				}
+				f.AddDropDown(label, field.Values, selected, nil)
+				continue
+			}
 			f.AddInputField(label, j.string(fieldname), 0, nil, nil)
 		case "bool":
 			f.AddCheckbox(label, j.bool(fieldname), nil)
+		case "float64":
+			f.AddInputField(label, j.string(fieldname), 0, nil, nil)
 		}
 	}
 	return f
@@ -72,11 +86,19 @@ func (e *Edit) UpdateAction() {
 	f := e.Form
 	jh := NewJsonMapWrapper(e.jsonMap)
 
-	ExtractFieldValues(jh, f)
+	if err := ExtractFieldValues(jh, f); err != nil {
+		e.Search.App.SetStatus("[black:red]" + err.Error() + "[white]")
+		return
+	}
 
 	doc, err := MiniDocFrom(e.jsonMap)
 	if err != nil {
 		log.Errorf("MiniDocFrom failed: %v", err)
+		e.Search.App.SetStatus("[black:red]" + err.Error() + "[white]")
+		return
+	}
+	if err := ValidateDoc(doc); err != nil {
+		e.Search.App.SetStatus("[black:red]" + err.Error() + "[white]")
 		return
 	}
 	log.Debugf("minidoc from json: %v", e.jsonMap)
@@ -90,9 +112,23 @@ func (e *Edit) UpdateAction() {
 	e.Search.UnLoadEdit()
 }
 
-func ExtractFieldValues(jh *JsonMapWrapper, f *tview.Form) {
+// validated docs check the values entered in the edit form before they are written
+type validated interface {
+	Validate() error
+}
+
+// ValidateDoc checks doc when its doctype validates what is entered
+func ValidateDoc(doc MiniDoc) error {
+	if v, ok := doc.(validated); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+// ExtractFieldValues copies the form values into the json map, numbers that do not parse are reported
+func ExtractFieldValues(jh *JsonMapWrapper, f *tview.Form) error {
 	for fieldName, _ := range jh.fields() {
-		if fieldName == "type" || fieldName == "id" || fieldName == "created_date" || fieldName == "fragments" {
+		if fieldName == "type" || fieldName == "id" || fieldName == "created_date" || fieldName == "updated_date" || fieldName == "fragments" {
 			continue
 		}
 
@@ -101,6 +137,9 @@ func ExtractFieldValues(jh *JsonMapWrapper, f *tview.Form) {
 		case "string":
 			fieldNameCleaned := strings.Replace(fieldName, "_", " ", -1)
 			sptr := GetInputValue(f, fieldNameCleaned+":")
+			if sptr == nil {
+				sptr = GetDropDownValue(f, fieldNameCleaned+":")
+			}
 			if sptr != nil {
 				jh.set(fieldName, *sptr)
 			}
@@ -108,7 +147,10 @@ func ExtractFieldValues(jh *JsonMapWrapper, f *tview.Form) {
 			fieldNameCleaned := strings.Replace(fieldName, "_", " ", -1)
 			sptr := GetInputValue(f, fieldNameCleaned+":")
 			if sptr != nil {
-				fv, _ := strconv.ParseFloat(*sptr, 64)
+				fv, err := strconv.ParseFloat(strings.TrimSpace(*sptr), 64)
+				if err != nil && len(strings.TrimSpace(*sptr)) > 0 {
+					return fmt.Errorf("%s: expected a number but got '%s'", fieldNameCleaned, *sptr)
+				}
 				jh.set(fieldName, fv)
 			}
 		case "bool":
@@ -120,6 +162,7 @@ func ExtractFieldValues(jh *JsonMapWrapper, f *tview.Form) {
 			log.Errorf("setting %s: %v", fieldName, jh.err.Error())
 		}
 	}
+	return nil
 }
 
 func (e *Edit) DeleteAction() {
@@ -3,7 +3,11 @@ package minidoc
 import (
 	"bufio"
 	"fmt"
+	"github.com/mitchellh/go-homedir"
+	"io"
 	"os/exec"
+	"regexp"
+	"strings"
 )
 
 // Execute execute
@@ -50,3 +54,48 @@ func Exec(args []string) error {
 
 	return err
 }
+
+// placeholderPattern finds the {{parameters}} of a runnable command
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([a-zA-Z0-9_\-]+)\s*\}\}`)
+
+// commandParameters lists the placeholders of command in the order they first show up
+func commandParameters(command string) []string {
+	params := []string{}
+	for _, match := range placeholderPattern.FindAllStringSubmatch(command, -1) {
+		if !contains(params, match[1]) {
+			params = append(params, match[1])
+		}
+	}
+	return params
+}
+
+// expandCommand fills the placeholders of command with values, placeholders without a value are left empty
+func expandCommand(command string, values map[string]string) string {
+	return placeholderPattern.ReplaceAllStringFunc(command, func(placeholder string) string {
+		return values[placeholderPattern.FindStringSubmatch(placeholder)[1]]
+	})
+}
+
+// RunCommand runs command with sh in dir, ~ is expanded, and returns its exit code
+func RunCommand(command, dir string, stdin io.Reader, stdout, stderr io.Writer) (int, error) {
+	cmd := exec.Command("sh", "-c", command)
+	if len(strings.TrimSpace(dir)) > 0 {
+		expanded, err := homedir.Expand(strings.TrimSpace(dir))
+		if err != nil {
+			return -1, err
+		}
+		cmd.Dir = expanded
+	}
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err := cmd.Run()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return -1, err
+	}
+	return 0, nil
+}
@@ -0,0 +1,97 @@
+package minidoc
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCommandParameters(t *testing.T) {
+	command := "kubectl logs {{pod}} -n {{ namespace }} | grep {{pod}}"
+	params := commandParameters(command)
+	if len(params) != 2 || params[0] != "pod" || params[1] != "namespace" {
+		t.Logf("unexpected parameters %v", params)
+		t.Fail()
+	}
+
+	expanded := expandCommand(command, map[string]string{"pod": "web-1", "namespace": "prod"})
+	if expanded != "kubectl logs web-1 -n prod | grep web-1" {
+		t.Logf("unexpected command %q", expanded)
+		t.Fail()
+	}
+
+	values := parseParameters(formatParameters(params, map[string]string{"pod": "a=b", "namespace": ""}))
+	if values["pod"] != "a=b" || values["namespace"] != "" {
+		t.Logf("unexpected parameter values %v", values)
+		t.Fail()
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "minidoc")
+	if err != nil {
+		t.Logf("creating temp dir: %v", err)
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+
+	var stdout bytes.Buffer
+	exitCode, err := RunCommand("pwd; exit 3", dir, nil, &stdout, &stdout)
+	if err != nil || exitCode != 3 || !strings.Contains(stdout.String(), dir) {
+		t.Logf("expected exit code 3 from %s but got %d %q: %v", dir, exitCode, stdout.String(), err)
+		t.Fail()
+	}
+
+	exitCode, err = RunCommand("true", "", nil, &stdout, &stdout)
+	if err != nil || exitCode != 0 {
+		t.Logf("expected exit code 0 but got %d: %v", exitCode, err)
+		t.Fail()
+	}
+}
+
+func TestIndexHandler_CommandLastRun(t *testing.T) {
+	indexer := NewIndexHandler(WithIndexHandlerInMemory())
+	defer indexer.Close()
+
+	command := &CommandDoc{BaseDoc: BaseDoc{ID: 1, Type: "command"}, Command: "make release", LastRun: "2020-03-01 10:00:00"}
+	indexer.Index(command)
+
+	for _, q := range []string{"release", "last_run:2020-03-01", "type:command"} {
+		result, err := indexer.Search(q, 0, 0)
+		if err != nil || len(result.Docs) != 1 || result.Docs[0].GetIDString() != "command:1" {
+			t.Logf("%s: expected command:1 but found %v: %v", q, result, err)
+			t.Fail()
+		}
+	}
+}
+
+func TestSearch_WriteChanged(t *testing.T) {
+	dh := NewTestDataHandler()
+	defer dh.Close()
+	s := &Search{App: &SimpleApp{DataHandler: dh}}
+
+	command := &CommandDoc{BaseDoc: BaseDoc{Type: "command"}, Command: "make release"}
+	dh.Write(command)
+
+	command.ExitCode = 2
+	command.LastRun = "2020-03-01 10:00:00"
+	// the run button and the event delegate both hand in the same run
+	for i := 0; i < 2; i++ {
+		if err := s.WriteChanged(command); err != nil {
+			t.Log(err)
+			t.Fail()
+		}
+	}
+
+	var revisions []Revision
+	dh.Store.View(func(tx *BucketTx) error {
+		revisions, _ = tx.History(command.GetID(), "command")
+		return nil
+	})
+	if len(revisions) != 1 {
+		t.Logf("expected a single revision for a single run but got %d", len(revisions))
+		t.Fail()
+	}
+}
@@ -0,0 +1,335 @@
+package minidoc
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/7onetella/minidoc/config"
+	"sort"
+	"strings"
+)
+
+// field types of a DocField
+const (
+	fieldTypeString    = "string"
+	fieldTypeBool      = "bool"
+	fieldTypeMultiline = "multiline"
+	fieldTypeDate      = "date"
+	fieldTypeEnum      = "enum"
+)
+
+// DocField is a field of a doctype declared in the config file
+type DocField struct {
+	Name string `mapstructure:"name"`
+	// Type is one of string, bool, multiline, date or enum, string when left out. A date is entered as
+	// 2006-01-02, today, tomorrow or yesterday and anything else is refused by the edit form
+	Type string `mapstructure:"type"`
+	// Values are the choices of an enum
+	Values []string `mapstructure:"values"`
+}
+
+// DocTypeConfig declares a doctype under doctypes in ~/.minidoc.yml, e.g.
+//
+//	doctypes:
+//	  book:
+//	    title: title
+//	    toggle: read
+//	    fields:
+//	      - {name: title}
+//	      - {name: author}
+//	      - {name: status, type: enum, values: [to-read, reading, done]}
+//	      - {name: read, type: bool}
+//	      - {name: finished, type: date}
+//	      - {name: review, type: multiline}
+type DocTypeConfig struct {
+	// Title is the field the doc is titled by, the first field when left out
+	Title string `mapstructure:"title"`
+	// Toggle is the bool field t toggles
+	Toggle string     `mapstructure:"toggle"`
+	Fields []DocField `mapstructure:"fields"`
+}
+
+// reservedFieldNames are kept by minidoc itself and cannot be declared
+var reservedFieldNames = []string{"id", "type", "tags", "created_date", "updated_date"}
+
+// baseFieldNames are the json fields of BaseDoc, a FieldDoc keeps these in its BaseDoc rather than in Fields
+func baseFieldNames() []string {
+	names := []string{}
+	jsonMap, _ := JsonMapFrom(&BaseDoc{}).(map[string]interface{})
+	for name := range jsonMap {
+		names = append(names, name)
+	}
+	return names
+}
+
+// FieldDoc is a doc whose own fields live in a map, it backs the doctypes declared in the config file
+type FieldDoc struct {
+	BaseDoc
+	Fields map[string]interface{}
+}
+
+func newFieldDoc(fields []DocField) *FieldDoc {
+	doc := &FieldDoc{Fields: map[string]interface{}{}}
+	base := baseFieldNames()
+	for _, field := range fields {
+		if contains(base, field.Name) {
+			continue
+		}
+		switch field.Type {
+		case fieldTypeBool:
+			doc.Fields[field.Name] = false
+		case fieldTypeEnum:
+			doc.Fields[field.Name] = field.Values[0]
+		default:
+			doc.Fields[field.Name] = ""
+		}
+	}
+	return doc
+}
+
+// MarshalJSON flattens Fields next to the BaseDoc fields
+func (d *FieldDoc) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(d.BaseDoc)
+	if err != nil {
+		return nil, err
+	}
+	jsonMap := map[string]interface{}{}
+	if err := json.Unmarshal(data, &jsonMap); err != nil {
+		return nil, err
+	}
+	for name, value := range d.Fields {
+		jsonMap[name] = value
+	}
+	return json.Marshal(jsonMap)
+}
+
+// UnmarshalJSON fills the BaseDoc fields and keeps every other field in Fields
+func (d *FieldDoc) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &d.BaseDoc); err != nil {
+		return err
+	}
+	jsonMap := map[string]interface{}{}
+	if err := json.Unmarshal(data, &jsonMap); err != nil {
+		return err
+	}
+	if d.Fields == nil {
+		d.Fields = map[string]interface{}{}
+	}
+	base := baseFieldNames()
+	for name, value := range jsonMap {
+		if !contains(base, name) {
+			d.Fields[name] = value
+		}
+	}
+	return nil
+}
+
+func (d *FieldDoc) GetJSON() interface{} {
+	return JsonMapFrom(d)
+}
+
+func (d *FieldDoc) GetTitle() string {
+	dt, found := docTypeNamed(d.Type)
+	if !found {
+		return d.Title
+	}
+	if value, ok := d.Fields[dt.TitleField]; ok {
+		return fmt.Sprintf("%v", value)
+	}
+	return d.Title
+}
+
+func (d *FieldDoc) GetMarkdown() string {
+	jsonMap, _ := d.GetJSON().(map[string]interface{})
+	markdown := "## " + d.GetTitle() + "\n"
+	dt, found := docTypeNamed(d.Type)
+	if !found {
+		return markdown
+	}
+	for _, field := range dt.Fields {
+		value := ""
+		if v, ok := jsonMap[field.Name]; ok && v != nil {
+			value = fmt.Sprintf("%v", v)
+		}
+		if field.Type == fieldTypeMultiline {
+			markdown += fmt.Sprintf("\n- **%s**:\n\n```\n%s\n```\n", field.Name, value)
+			continue
+		}
+		markdown += fmt.Sprintf("\n- **%s**: %s", field.Name, value)
+	}
+	return markdown
+}
+
+func (d *FieldDoc) toggleField() string {
+	if dt, found := docTypeNamed(d.Type); found {
+		return dt.ToggleField
+	}
+	return ""
+}
+
+func (d *FieldDoc) GetToggleValueAsString() string {
+	if d.GetToggle() {
+		return "✓️"
+	}
+	return " "
+}
+
+func (d *FieldDoc) SetToggle(toggle bool) {
+	if field := d.toggleField(); len(field) > 0 {
+		d.Fields[field] = toggle
+	}
+}
+
+func (d *FieldDoc) GetToggle() bool {
+	toggled, _ := d.Fields[d.toggleField()].(bool)
+	return toggled
+}
+
+// Validate checks the dates entered in the edit form, today, tomorrow and yesterday are turned into dates
+func (d *FieldDoc) Validate() error {
+	dt, found := docTypeNamed(d.Type)
+	if !found {
+		return nil
+	}
+	for _, field := range dt.Fields {
+		value, _ := d.Fields[field.Name].(string)
+		if field.Type != fieldTypeDate || len(strings.TrimSpace(value)) == 0 {
+			continue
+		}
+		date, err := parseQueryDate(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("%s: %v", field.Name, err)
+		}
+		d.Fields[field.Name] = date.Format(queryDateFormat)
+	}
+	return nil
+}
+
+// docFieldOf finds the declared field called name of doctype
+func docFieldOf(doctype, name string) (DocField, bool) {
+	if dt, found := docTypeNamed(doctype); found {
+		for _, field := range dt.Fields {
+			if field.Name == name {
+				return field, true
+			}
+		}
+	}
+	return DocField{}, false
+}
+
+// DocTypeFromConfig turns a doctype declared in the config file into one that can be registered
+func DocTypeFromConfig(name string, c DocTypeConfig) (DocType, error) {
+	if len(c.Fields) == 0 {
+		return DocType{}, fmt.Errorf("doctype %s declares no fields", name)
+	}
+
+	fields := []DocField{}
+	fieldNames := []string{}
+	spec := IndexSpec{}
+	viEditFields := []string{}
+	for _, field := range c.Fields {
+		if len(field.Type) == 0 {
+			field.Type = fieldTypeString
+		}
+		switch {
+		case len(field.Name) == 0:
+			return DocType{}, fmt.Errorf("doctype %s: field without a name", name)
+		case contains(reservedFieldNames, field.Name):
+			return DocType{}, fmt.Errorf("doctype %s: field name %s is reserved", name, field.Name)
+		case contains(fieldNames, field.Name):
+			return DocType{}, fmt.Errorf("doctype %s: field %s declared twice", name, field.Name)
+		}
+
+		switch field.Type {
+		case fieldTypeString:
+			spec.Text = append(spec.Text, field.Name)
+		case fieldTypeMultiline:
+			spec.Text = append(spec.Text, field.Name)
+			viEditFields = append(viEditFields, field.Name)
+		case fieldTypeBool:
+			spec.Bool = append(spec.Bool, field.Name)
+		case fieldTypeDate:
+			spec.Date = append(spec.Date, field.Name)
+		case fieldTypeEnum:
+			if len(field.Values) == 0 {
+				return DocType{}, fmt.Errorf("doctype %s: enum %s has no values", name, field.Name)
+			}
+			spec.Keyword = append(spec.Keyword, field.Name)
+		default:
+			return DocType{}, fmt.Errorf("doctype %s: field %s has unknown type %s", name, field.Name, field.Type)
+		}
+		fields = append(fields, field)
+		fieldNames = append(fieldNames, field.Name)
+	}
+
+	title := c.Title
+	if len(title) == 0 {
+		title = fieldNames[0]
+	}
+	if !contains(fieldNames, title) {
+		return DocType{}, fmt.Errorf("doctype %s: title %s is not one of its fields", name, title)
+	}
+	spec.SortTitle = title
+
+	if len(c.Toggle) > 0 {
+		if field, ok := findDocField(fields, c.Toggle); !ok || field.Type != fieldTypeBool {
+			return DocType{}, fmt.Errorf("doctype %s: toggle %s is not a bool field", name, c.Toggle)
+		}
+	}
+
+	actions := "*"
+	if len(c.Toggle) > 0 {
+		actions = "t <- toggle " + c.Toggle
+	}
+
+	return DocType{
+		Name:          name,
+		New:           func() MiniDoc { return newFieldDoc(fields) },
+		Index:         spec,
+		ToggleField:   c.Toggle,
+		DisplayFields: append(append([]string{"id", "type"}, fieldNames...), "tags", "created_date", "updated_date"),
+		EditFields:    append(append([]string{}, fieldNames...), "tags"),
+		ViEditFields:  viEditFields,
+		Actions:       actions,
+		Fields:        fields,
+		TitleField:    title,
+	}, nil
+}
+
+func findDocField(fields []DocField, name string) (DocField, bool) {
+	for _, field := range fields {
+		if field.Name == name {
+			return field, true
+		}
+	}
+	return DocField{}, false
+}
+
+// RegisterConfigDocTypes registers the doctypes declared under doctypes in the config file, a doctype that
+// does not check out is left out and reported in the returned error
+func RegisterConfigDocTypes(cfg config.Provider) error {
+	declared := map[string]DocTypeConfig{}
+	if err := cfg.UnmarshalKey("doctypes", &declared); err != nil {
+		return err
+	}
+
+	names := []string{}
+	for name := range declared {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	failed := []string{}
+	for _, name := range names {
+		dt, err := DocTypeFromConfig(name, declared[name])
+		if err == nil {
+			err = RegisterDocType(dt)
+		}
+		if err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%s", strings.Join(failed, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package minidoc
+
+import (
+	"encoding/json"
+	"github.com/7onetella/minidoc/config"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRegisterConfigDocTypes(t *testing.T) {
+	registered := append([]string{}, doctypes...)
+	defer func() {
+		delete(docTypeRegistry, "book")
+		doctypes = registered
+		config.Config().Set("doctypes", nil)
+	}()
+
+	config.Config().Set("doctypes", map[string]interface{}{
+		"book": map[string]interface{}{
+			"toggle": "read",
+			"fields": []interface{}{
+				map[string]interface{}{"name": "title"},
+				map[string]interface{}{"name": "author"},
+				map[string]interface{}{"name": "status", "type": "enum", "values": []interface{}{"to-read", "reading", "done"}},
+				map[string]interface{}{"name": "read", "type": "bool"},
+				map[string]interface{}{"name": "finished", "type": "date"},
+				map[string]interface{}{"name": "review", "type": "multiline"},
+			},
+		},
+		"broken": map[string]interface{}{
+			"fields": []interface{}{
+				map[string]interface{}{"name": "rating", "type": "stars"},
+			},
+		},
+	})
+	if err := RegisterConfigDocTypes(config.Config()); err == nil || !strings.Contains(err.Error(), "stars") {
+		t.Logf("expected the broken doctype reported but got %v", err)
+		t.Fail()
+	}
+	if _, found := docTypeNamed("broken"); found {
+		t.Log("expected the broken doctype left out")
+		t.Fail()
+	}
+
+	doc, err := NewDoc("book")
+	if err != nil {
+		t.Logf("instantiating book: %v", err)
+		t.FailNow()
+	}
+	jsonMap := JsonMapFrom(doc)
+	jh := NewJsonMapWrapper(jsonMap)
+	jh.set("title", "Dune")
+	jh.set("author", "Frank Herbert")
+	jh.set("status", "reading")
+	jh.set("finished", "2020-03-01")
+	jh.set("review", "sand\nand spice")
+	book, err := MiniDocFrom(jsonMap)
+	if err != nil {
+		t.Logf("converting book: %v", err)
+		t.FailNow()
+	}
+	book.SetID(1)
+	book.SetToggle(true)
+
+	data, _ := json.Marshal(book)
+	roundTrip, _ := NewDoc("book")
+	if err := json.Unmarshal(data, roundTrip); err != nil || roundTrip.GetTitle() != "Dune" || !roundTrip.GetToggle() {
+		t.Logf("unexpected book after round trip %s: %v", data, err)
+		t.Fail()
+	}
+	if !strings.Contains(book.GetMarkdown(), "- **author**: Frank Herbert") {
+		t.Logf("unexpected markdown %q", book.GetMarkdown())
+		t.Fail()
+	}
+
+	indexer := NewIndexHandler(WithIndexHandlerInMemory())
+	defer indexer.Close()
+	indexer.Index(book)
+
+	tests := []string{"herbert", "status:reading", "read:true", "finished:2020-03-01", "spice"}
+	for _, q := range tests {
+		result, err := indexer.Search(q, 0, 0)
+		if err != nil || len(result.Docs) != 1 || result.Docs[0].GetIDString() != "book:1" {
+			t.Logf("%s: expected book:1 but found %v: %v", q, result, err)
+			t.Fail()
+		}
+	}
+
+	jh.set("finished", "someday")
+	invalid, _ := MiniDocFrom(jsonMap)
+	if err := ValidateDoc(invalid); err == nil || !strings.Contains(err.Error(), "finished") {
+		t.Logf("expected the finished date rejected but got %v", err)
+		t.Fail()
+	}
+
+	jh.set("finished", "today")
+	valid, _ := MiniDocFrom(jsonMap)
+	if err := ValidateDoc(valid); err != nil || valid.(*FieldDoc).Fields["finished"] != time.Now().Format(queryDateFormat) {
+		t.Logf("expected today turned into a date but got %v: %v", valid.(*FieldDoc).Fields["finished"], err)
+		t.Fail()
+	}
+}
+
+func TestDocTypeFromConfig(t *testing.T) {
+	failing := map[string]DocTypeConfig{
+		"no fields":      {},
+		"reserved field": {Fields: []DocField{{Name: "tags"}}},
+		"enum no values": {Fields: []DocField{{Name: "status", Type: "enum"}}},
+		"unknown title":  {Title: "name", Fields: []DocField{{Name: "title"}}},
+		"string toggle":  {Toggle: "title", Fields: []DocField{{Name: "title"}}},
+		"twice declared": {Fields: []DocField{{Name: "title"}, {Name: "title"}}},
+	}
+	for name, c := range failing {
+		if _, err := DocTypeFromConfig("book", c); err == nil {
+			t.Logf("%s: expected an error", name)
+			t.Fail()
+		}
+	}
+
+	dt, err := DocTypeFromConfig("book", DocTypeConfig{Fields: []DocField{{Name: "title"}, {Name: "review", Type: "multiline"}}})
+	if err != nil || dt.TitleField != "title" || len(dt.ViEditFields) != 1 || len(dt.Index.Text) != 2 {
+		t.Logf("unexpected doctype %v: %v", dt, err)
+		t.Fail()
+	}
+}
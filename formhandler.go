@@ -26,3 +26,16 @@ func GetCheckBoxChecked(form *tview.Form, label string) bool {
 	}
 	return false
 }
+
+func GetDropDownValue(form *tview.Form, label string) *string {
+	fi := form.GetFormItemByLabel(label)
+	if fi == nil {
+		return nil
+	}
+	dropdown, ok := fi.(*tview.DropDown)
+	if ok {
+		_, v := dropdown.GetCurrentOption()
+		return &v
+	}
+	return nil
+}
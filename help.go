@@ -32,6 +32,7 @@ func (h *Help) Page() (title string, content tview.Primitive) {
 
        Ctrl-c  <-  Exit
        Ctrl-n  <-  New note
+       Ctrl-s  <-  New snippet, its language picks the highlighting and the vim filetype
        Ctrl-h  <-  Navigate to left menu item
        Ctrl-l  <-  Navigate to right menu item
        Ctrl-o  <-  Show debug view
@@ -58,13 +59,66 @@ func (h *Help) Page() (title string, content tview.Primitive) {
        k           <-  Move up
        i           <-  Load currently selected row in the edit view
        e           <-  Edit vim editable fields, e.g. note
-	   t           <-  Toggle toggle-able field
+	   t           <-  Toggle toggle-able field, a recurring todo moves to its next due day instead,
+                       todos past their due day show in red
        spacebar    <-  Select row
        Ctrl-j      <-  Move row down
        Ctrl-k      <-  Move row up
-       Ctrl-d      <-  Batch delete selected rows
-       Ctrl-a      <-  Select all / Deselect all
+       Ctrl-d      <-  Batch move selected rows to trash
+       Ctrl-a      <-  Select all / Deselect all, every page of the search result
+       n           <-  Next page of the search result
+       p           <-  Previous page of the search result
+       s           <-  Sort by score, created, updated, title or type in turn
        Ctrl-t      <-  Toggle all / Detoggle all
+       y           <-  Copy the bare snippet to the clipboard
+       r           <-  Run the command, values for its {{placeholders}} are asked for first,
+                       the exit code and the time of the run are kept on the doc
+
+    [black:darkcyan][Preview[][white]
+
+       n           <-  Show the next referenced doc
+       m           <-  Show docs like this one, pressing it again highlights the next one
+       g           <-  List the highlighted related doc in the result list
+
+    [black:darkcyan][Tags Page[][white]
+
+       Enter       <-  List docs tagged with the selected tag
+       r           <-  Refresh tag counts
+
+    [black:darkcyan][Search Commands[][white]
+
+       @new book   <-  Start a doc of any doctype, doctypes with typed fields (string, bool, multiline,
+                       date, enum) can be declared under doctypes in ~/.minidoc.yml
+       @history    <-  List revisions of the current row, r restores the selected revision
+       @trash      <-  List deleted docs, r restores and p purges the selected doc
+       @save name  <-  Pin the last search as a page of its own, it runs again every time the page is shown
+       @unsave name  <-  Remove a saved search
+       @collection-save name    <-  Keep the selected rows, or every row, in their current order as a collection
+       @collection name         <-  List a collection in its order, Ctrl-j and Ctrl-k reorder it for good,
+                                    @generate and @export follow that order
+       @collection-delete name  <-  Remove a collection, its docs stay
+       @tag-rename old new    <-  Rename a tag and the tags under it, e.g. lang/go, in every doc
+       @tag-merge a b into c  <-  Replace tags a and b with c in every doc
+
+    [black:darkcyan][Search Query Syntax[][white]
+
+       golang tips             <-  Docs with both words
+       "exact phrase"          <-  Words next to each other
+       tag:golang type:url     <-  Field filters, tag is short for tags
+       tag:lang                <-  Tag lang and tags under it such as lang/go
+       host:github.com path:/golang*
+                               <-  Host and path of urls
+       done:false              <-  Toggle fields, true or false
+       -draft  -tag:old        <-  Exclude docs
+       vim OR emacs            <-  Either side
+       gola*                   <-  Words starting with gola
+       created:2020-01-01..2020-02-01, created:>=2020-01-01, updated:<2020-03-01
+                               <-  Date ranges, whole days are included
+       due:<tomorrow           <-  today, tomorrow and yesterday stand in for dates
+       priority:1, priority:<=2, priority:1..2
+                               <-  Number ranges, todos run from priority 1, the highest, to 3
+       kubrenetes, kub         <-  Too few hits adds typo and prefix matches of plain words, the mode
+                                   of each row shows in the fragments column, exact matches rank first
 `)
 	return "Help", h.Content
 }
@@ -0,0 +1,79 @@
+package minidoc
+
+import (
+	"fmt"
+	"github.com/blevesearch/bleve/registry"
+	"github.com/blevesearch/bleve/search/highlight"
+	simpleFragmenter "github.com/blevesearch/bleve/search/highlight/fragmenter/simple"
+	simpleHighlighter "github.com/blevesearch/bleve/search/highlight/highlighter/simple"
+	"github.com/rivo/tview"
+	"strings"
+)
+
+// tviewHighlighterName highlights search fragments with tview color tags
+const tviewHighlighterName = "tview"
+
+const (
+	highlightColor = "[yellow]"
+	highlightReset = "[white]"
+)
+
+// tviewFragmentFormatter marks matched terms with tview color tags, the rest of the text is escaped so that
+// brackets in docs, e.g. [note:3], show as they are
+type tviewFragmentFormatter struct{}
+
+func (tviewFragmentFormatter) Format(f *highlight.Fragment, orderedTermLocations highlight.TermLocations) string {
+	rv := ""
+	curr := f.Start
+	for _, termLocation := range orderedTermLocations {
+		if termLocation == nil {
+			continue
+		}
+		if !termLocation.ArrayPositions.Equals(f.ArrayPositions) {
+			continue
+		}
+		if termLocation.Start < curr {
+			continue
+		}
+		if termLocation.End > f.End {
+			break
+		}
+		rv += tview.Escape(string(f.Orig[curr:termLocation.Start]))
+		rv += highlightColor + tview.Escape(string(f.Orig[termLocation.Start:termLocation.End])) + highlightReset
+		curr = termLocation.End
+	}
+	rv += tview.Escape(string(f.Orig[curr:f.End]))
+	return rv
+}
+
+func init() {
+	registry.RegisterFragmentFormatter(tviewHighlighterName, func(config map[string]interface{}, cache *registry.Cache) (highlight.FragmentFormatter, error) {
+		return tviewFragmentFormatter{}, nil
+	})
+	registry.RegisterHighlighter(tviewHighlighterName, func(config map[string]interface{}, cache *registry.Cache) (highlight.Highlighter, error) {
+		fragmenter, err := cache.FragmenterNamed(simpleFragmenter.Name)
+		if err != nil {
+			return nil, fmt.Errorf("error building fragmenter: %v", err)
+		}
+		formatter, err := cache.FragmentFormatterNamed(tviewHighlighterName)
+		if err != nil {
+			return nil, fmt.Errorf("error building fragment formatter: %v", err)
+		}
+		return simpleHighlighter.NewHighlighter(fragmenter, formatter, simpleHighlighter.DefaultSeparator), nil
+	})
+}
+
+// highlightMatched colors word when its analyzed form is one of the terms a search matched
+func highlightMatched(word string, matchedTerms map[string]bool) string {
+	if len(matchedTerms) == 0 {
+		return word
+	}
+	trimmed := strings.ToLower(strings.Trim(word, ".,;:!?()'\""))
+	if len(trimmed) == 0 {
+		return word
+	}
+	if matchedTerms[trimmed] || matchedTerms[stemmed(trimmed)] {
+		return highlightColor + word + "[darkcyan]"
+	}
+	return word
+}
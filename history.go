@@ -0,0 +1,223 @@
+package minidoc
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/rivo/tview"
+	"reflect"
+	"strings"
+)
+
+// revisionIgnoredFields change on every write so they alone don't make a new revision
+var revisionIgnoredFields = []string{"created_date", "updated_date", schemaVersionField}
+
+type Revision struct {
+	Number int
+	Doc    MiniDoc
+}
+
+func historyBucketName(doctype string) string {
+	return "_history_" + doctype
+}
+
+func revisionKeyPrefix(id uint32) []byte {
+	return []byte(fmt.Sprintf("%d:", id))
+}
+
+func revisionKey(id uint32, number int) []byte {
+	return []byte(fmt.Sprintf("%d:%010d", id, number))
+}
+
+// revisionNumber reads the revision number off a key made by revisionKey
+func revisionNumber(k, prefix []byte) (int, error) {
+	var number int
+	_, err := fmt.Sscanf(string(k[len(prefix):]), "%d", &number)
+	if err != nil {
+		log.Errorf("invalid revision key %s: %v", k, err)
+	}
+	return number, err
+}
+
+// keepRevision copies what is currently stored under id into the history bucket before it gets overwritten with data
+func (tx *BucketTx) keepRevision(doctype string, id uint32, data []byte) error {
+	prev := tx.kv.Get(doctype, toBytes(id))
+	if prev == nil || sameRevision(prev, data) {
+		return nil
+	}
+
+	// only the newest revision key is needed to number the next one
+	number := 1
+	prefix := revisionKeyPrefix(id)
+	if k, _ := tx.kv.LastWithPrefix(historyBucketName(doctype), prefix); k != nil {
+		last, err := revisionNumber(k, prefix)
+		if err != nil {
+			return err
+		}
+		number = last + 1
+	}
+
+	return tx.kv.Put(historyBucketName(doctype), revisionKey(id, number), prev)
+}
+
+// History returns previous versions of a doc, oldest first, each upgraded to the current schema
+func (tx *BucketTx) History(id uint32, doctype string) ([]Revision, error) {
+	revisions := []Revision{}
+	prefix := revisionKeyPrefix(id)
+	err := tx.kv.ForEachPrefix(historyBucketName(doctype), prefix, func(k, v []byte) error {
+		number, err := revisionNumber(k, prefix)
+		if err != nil {
+			return err
+		}
+
+		doc, err := NewDoc(doctype)
+		if err != nil {
+			log.Errorf("instantiating %s", doctype)
+			return err
+		}
+		data, err := migrateData(doctype, v)
+		if err != nil {
+			return err
+		}
+		err = json.Unmarshal(data, doc)
+		if err != nil {
+			log.Errorf("error while unmarshalling revision %s of %s:%d: %v", k, doctype, id, err)
+			return err
+		}
+
+		revisions = append(revisions, Revision{number, doc})
+		return nil
+	})
+	return revisions, err
+}
+
+func sameRevision(a, b []byte) bool {
+	var am, bm map[string]interface{}
+	if json.Unmarshal(a, &am) != nil || json.Unmarshal(b, &bm) != nil {
+		return false
+	}
+	for _, field := range revisionIgnoredFields {
+		delete(am, field)
+		delete(bm, field)
+	}
+	return reflect.DeepEqual(am, bm)
+}
+
+// ShowHistory lists revisions of doc in the result list, newest first
+func (s *Search) ShowHistory(doc MiniDoc) {
+	var revisions []Revision
+	err := s.App.DataHandler.Store.View(func(tx *BucketTx) error {
+		var err error
+		revisions, err = tx.History(doc.GetID(), doc.GetType())
+		return err
+	})
+	if err != nil {
+		s.App.SetStatus("[black:red]reading history: " + err.Error() + "[white]")
+		return
+	}
+	if len(revisions) == 0 {
+		s.App.SetStatus("[white:darkcyan] no revisions for " + doc.GetIDString() + "[white]")
+		return
+	}
+
+	newestFirst := make([]Revision, len(revisions))
+	for i, rev := range revisions {
+		newestFirst[len(revisions)-1-i] = rev
+	}
+
+	s.UpdateResult([]MiniDoc{})
+	s.HistoryDoc = doc
+	s.Revisions = newestFirst
+	for i, rev := range newestFirst {
+		jh := NewJsonMapWrapper(rev.Doc.GetJSON())
+		fragments := fmt.Sprintf("rev %d  %s  %s", rev.Number, jh.string("created_date"), rev.Doc.GetTitle())
+		cd := []CellData{
+			CellData{rev.Doc.GetType(), rev.Doc.GetIDString()},
+			CellData{false, " "},
+			CellData{IsToggled(rev.Doc), toggleMark(rev.Doc)},
+			CellData{rev.Doc.GetID(), ""},
+		}
+		cd = append(cd, s.ResultList.ColumnCells(rev.Doc)...)
+		cd = append(cd, CellData{fragments + cellpadding, fragments + cellpadding})
+		s.ResultList.SetColumnCells(i, cd)
+	}
+
+	s.ResultList.ScrollToBeginning()
+	s.SelectRow(0)
+	s.GoToSearchResult()
+}
+
+// IsHistoryMode tells whether the result list is showing revisions instead of search results
+func (s *Search) IsHistoryMode() bool {
+	return s.Revisions != nil
+}
+
+// PreviewRevision shows what changed between the revision at row and the current doc
+func (s *Search) PreviewRevision(row int) {
+	if row >= len(s.Revisions) {
+		return
+	}
+	rev := s.Revisions[row]
+
+	s.App.SetStatus("[white:darkcyan] r <- restore this revision | [red]- only in revision[white:darkcyan] | [green]+ only in current[white]",
+		fmt.Sprintf("row %d ", row))
+
+	content := ""
+	for _, line := range LineDiff(DocLines(rev.Doc), DocLines(s.HistoryDoc)) {
+		text := tview.Escape(line.Text)
+		switch line.Op {
+		case DiffRemoved:
+			content += "[red]- " + text + "[white]\n"
+		case DiffAdded:
+			content += "[green]+ " + text + "[white]\n"
+		default:
+			content += "[darkcyan]  " + text + "[white]\n"
+		}
+	}
+
+	s.Detail.SetTitle(fmt.Sprintf("%s rev %d", rev.Doc.GetIDString(), rev.Number))
+	s.Detail.Clear()
+	fmt.Fprint(s.Detail, content)
+}
+
+// RestoreRevision writes the revision at row back as the current doc, the replaced version is kept in history
+func (s *Search) RestoreRevision(row int) {
+	if row >= len(s.Revisions) {
+		return
+	}
+	rev := s.Revisions[row]
+
+	_, err := s.App.DataHandler.Write(rev.Doc)
+	if err != nil {
+		log.Errorf("restoring %s rev %d: %v", rev.Doc.GetIDString(), rev.Number, err)
+		s.App.SetStatus("[black:red]restoring: " + err.Error() + "[white]")
+		return
+	}
+
+	doc, err := s.App.DataHandler.Store.Read(rev.Doc.GetID(), rev.Doc.GetType())
+	if err != nil {
+		return
+	}
+	s.ShowHistory(doc)
+	s.App.SetStatus(fmt.Sprintf("[white:darkcyan] %s restored to rev %d[white]", doc.GetIDString(), rev.Number))
+}
+
+// DocLines flattens the display fields of doc into lines for diffing
+func DocLines(doc MiniDoc) []string {
+	jh := NewJsonMapWrapper(JsonMapFrom(doc))
+
+	lines := []string{}
+	for _, fieldName := range doc.GetDisplayFields() {
+		if fieldName == "type" || fieldName == "id" || contains(revisionIgnoredFields, fieldName) {
+			continue
+		}
+		fieldNameCleaned := strings.Replace(fieldName, "_", " ", -1)
+		values := strings.Split(jh.string(fieldName), "\n")
+		if len(values) > 1 {
+			lines = append(lines, fieldNameCleaned+":")
+			lines = append(lines, values...)
+			continue
+		}
+		lines = append(lines, fieldNameCleaned+": "+values[0])
+	}
+	return lines
+}
@@ -1,23 +1,31 @@
 package minidoc
 
 import (
+	"context"
 	"fmt"
 	"github.com/blevesearch/bleve"
 	_ "github.com/blevesearch/bleve/config"
-	"github.com/blevesearch/bleve/search/highlight/highlighter/ansi"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
-	//"github.com/blevesearch/bleve/search/highlight/format/ansi"
-	"github.com/blevesearch/bleve/analysis/analyzer/keyword"
+	"github.com/blevesearch/bleve/analysis/analyzer/custom"
 	"github.com/blevesearch/bleve/analysis/lang/en"
+	"github.com/blevesearch/bleve/analysis/token/lowercase"
+	"github.com/blevesearch/bleve/analysis/tokenizer/single"
+	"github.com/blevesearch/bleve/analysis/tokenizer/whitespace"
 	"github.com/blevesearch/bleve/mapping"
 	"github.com/blevesearch/bleve/search"
+	"github.com/blevesearch/bleve/search/query"
+	"github.com/rivo/tview"
 )
 
 const (
 	ErrorGeneric Error = iota
 	ErrorCSVDoesNotExist
+	ErrorTxNotWritable
 )
 
 type Error int
@@ -29,12 +37,74 @@ func (e Error) Error() string {
 var errorMessages = map[Error]string{
 	ErrorGeneric:         "generic error",
 	ErrorCSVDoesNotExist: "cannot open csv, path does not exist",
+	ErrorTxNotWritable:   "transaction not writable",
+}
+
+// Indexer keeps minidocs searchable
+type Indexer interface {
+	Index(doc MiniDoc) error
+	IndexAll(docs []MiniDoc) error
+	Delete(doc MiniDoc) error
+	DeleteAll(docs []MiniDoc) error
+	Search(queryString string, offset, limit int) (*SearchResult, error)
+	SearchContext(ctx context.Context, queryString string, offset, limit int) (*SearchResult, error)
+	Tags() ([]TagCount, error)
+	Similar(doc MiniDoc, limit int) ([]MiniDoc, error)
+	SetSortOrder(order ...string)
+	Close() error
+}
+
+// SearchResult is one page of hits for a query, Tags counts the tags of every hit not just the page
+type SearchResult struct {
+	Docs   []MiniDoc
+	Total  int
+	Offset int
+	Took   time.Duration
+	Tags   []TagCount
+	// Fallback is set when prefix and fuzzy matches were added because the query found too few docs
+	Fallback bool
+}
+
+// TagCount is the number of docs carrying Tag
+type TagCount struct {
+	Tag   string
+	Count int
+}
+
+const tagsFacetName = "tags"
+
+// tagFacetSize is how many of the most used tags come with a search result
+const tagFacetSize = 10
+
+// maxTags caps the tags listed by Tags
+const maxTags = 10000
+
+// Stat describes the page for the status bar
+func (sr *SearchResult) Stat() string {
+	took := strings.ReplaceAll(sr.Took.String(), "µ", "u")
+	stat := fmt.Sprintf("%d matches, showing %d through %d, took %s", sr.Total, sr.Offset+1, sr.Offset+len(sr.Docs), took)
+	if sr.Fallback {
+		stat += ", including prefix and fuzzy matches"
+	}
+	return stat
+}
+
+// HasMore tells whether there are hits after this page
+func (sr *SearchResult) HasMore() bool {
+	return sr.Offset+len(sr.Docs) < sr.Total
 }
 
 type IndexHandler struct {
 	debug     func(string)
 	index     bleve.Index
 	indexPath string
+	inMemory  bool
+	sortOrder search.SortOrder
+	mu        sync.Mutex
+	// fuzzyMinHits is the number of exact hits below which prefix and fuzzy matches are added, 0 turns it off
+	fuzzyMinHits int
+	// dirty collects ids touched while Rebuild runs so they can be caught up before the swap
+	dirty map[string]bool
 }
 
 type IndexHandlerOption func(*IndexHandler)
@@ -52,18 +122,67 @@ func WithIndexHandlerIndexPath(indexPath string) IndexHandlerOption {
 	}
 }
 
+// WithIndexHandlerInMemory keeps the index in memory only, nothing is written to the index path
+func WithIndexHandlerInMemory() IndexHandlerOption {
+	return func(ih *IndexHandler) {
+		ih.inMemory = true
+	}
+}
+
+// WithIndexHandlerSortOrder sorts search results by the given fields, see SetSortOrder
+func WithIndexHandlerSortOrder(order ...string) IndexHandlerOption {
+	return func(ih *IndexHandler) {
+		ih.SetSortOrder(order...)
+	}
+}
+
+// WithIndexHandlerFuzzyFallback adds prefix and fuzzy matches to searches finding fewer than minHits docs,
+// a minHits of 0 keeps searches exact
+func WithIndexHandlerFuzzyFallback(minHits int) IndexHandlerOption {
+	return func(ih *IndexHandler) {
+		ih.fuzzyMinHits = minHits
+	}
+}
+
 const indexPathDefault = ".minidoc/index"
 
+// fuzzyMinHitsDefault is the fuzzy fallback minimum unless WithIndexHandlerFuzzyFallback says otherwise
+const fuzzyMinHitsDefault = 3
+
 func NewIndexHandler(opts ...IndexHandlerOption) *IndexHandler {
 	ih := &IndexHandler{
-		indexPath: indexPathDefault,
-		debug:     func(string) {},
+		indexPath:    indexPathDefault,
+		debug:        func(string) {},
+		sortOrder:    search.SortOrder{&search.SortScore{Desc: true}},
+		fuzzyMinHits: fuzzyMinHitsDefault,
 	}
 
 	for _, opt := range opts {
 		opt(ih)
 	}
 
+	if ih.inMemory {
+		mapping, err := IndexMapping()
+		if err != nil {
+			log.Fatalf("error during loading index mapping: %v", err)
+			return nil
+		}
+		index, err := bleve.NewMemOnly(mapping)
+		if err != nil {
+			log.Fatalf("error during creating in memory index: %v", err)
+			return nil
+		}
+		if err := setMappingHash(index); err != nil {
+			log.Fatalf("error while storing mapping hash: %v", err)
+			return nil
+		}
+		ih.index = index
+		log.Debug("in memory index created successfully")
+		return ih
+	}
+
+	recoverInterruptedSwap(ih.indexPath)
+
 	index, err := bleve.Open(ih.indexPath)
 	if err == bleve.ErrorIndexPathDoesNotExist {
 		mapping, err := IndexMapping()
@@ -76,6 +195,10 @@ func NewIndexHandler(opts ...IndexHandlerOption) *IndexHandler {
 			log.Fatalf("error during loading index: %v", err)
 			return nil
 		}
+		if err := setMappingHash(index); err != nil {
+			log.Fatalf("error while storing mapping hash: %v", err)
+			return nil
+		}
 	}
 	ih.index = index
 	log.Debug("index loaded successfully")
@@ -84,39 +207,229 @@ func NewIndexHandler(opts ...IndexHandlerOption) *IndexHandler {
 }
 
 func (ih *IndexHandler) Delete(doc MiniDoc) error {
+	ih.mu.Lock()
+	defer ih.mu.Unlock()
+
+	ih.markDirty(doc)
 	return ih.index.Delete(doc.GetIDString())
 }
 
 func (ih *IndexHandler) Index(doc MiniDoc) error {
-	return ih.index.Index(doc.GetIDString(), doc.GetJSON())
+	ih.mu.Lock()
+	defer ih.mu.Unlock()
+
+	ih.markDirty(doc)
+	return ih.index.Index(doc.GetIDString(), indexedDocument(doc))
+}
+
+// IndexAll indexes docs in a single batch
+func (ih *IndexHandler) IndexAll(docs []MiniDoc) error {
+	ih.mu.Lock()
+	defer ih.mu.Unlock()
+
+	batch := ih.index.NewBatch()
+	for _, doc := range docs {
+		ih.markDirty(doc)
+		if err := batch.Index(doc.GetIDString(), indexedDocument(doc)); err != nil {
+			return err
+		}
+	}
+	return ih.index.Batch(batch)
+}
+
+// DeleteAll removes docs from the index in a single batch
+func (ih *IndexHandler) DeleteAll(docs []MiniDoc) error {
+	ih.mu.Lock()
+	defer ih.mu.Unlock()
+
+	batch := ih.index.NewBatch()
+	for _, doc := range docs {
+		ih.markDirty(doc)
+		batch.Delete(doc.GetIDString())
+	}
+	return ih.index.Batch(batch)
+}
+
+func (ih *IndexHandler) markDirty(doc MiniDoc) {
+	if ih.dirty != nil {
+		ih.dirty[doc.GetIDString()] = true
+	}
+}
+
+// SetSortOrder sorts search results by fields such as "-updated_date" or "created_date", "-_score" is the default
+func (ih *IndexHandler) SetSortOrder(order ...string) {
+	ih.mu.Lock()
+	defer ih.mu.Unlock()
+
+	if len(order) == 0 {
+		ih.sortOrder = search.SortOrder{&search.SortScore{Desc: true}}
+		return
+	}
+	ih.sortOrder = search.ParseSortOrderStrings(order)
+}
+
+func (ih *IndexHandler) Close() error {
+	ih.mu.Lock()
+	defer ih.mu.Unlock()
+
+	return ih.index.Close()
 }
 
-// indexCmd will index given csv file
-func (ih *IndexHandler) Search(queryString string) ([]MiniDoc, string) {
+// Search runs queryString written in the query syntax of ParseQuery and returns limit hits starting at offset,
+// a limit of 0 or less returns every hit
+func (ih *IndexHandler) Search(queryString string, offset, limit int) (*SearchResult, error) {
+	return ih.SearchContext(context.Background(), queryString, offset, limit)
+}
+
+// SearchContext is Search that gives up as soon as ctx is cancelled. When the query finds fewer than the
+// fuzzy fallback minimum of hits, plain terms are searched again by prefix and with typos, exact matches rank first
+func (ih *IndexHandler) SearchContext(ctx context.Context, queryString string, offset, limit int) (*SearchResult, error) {
 	log.Debug("index search")
 
-	// search for some text
-	query := bleve.NewMatchQuery(queryString)
+	q, err := ParseQuery(queryString)
+	if err != nil {
+		return nil, err
+	}
+
+	ih.mu.Lock()
+	sortOrder := ih.sortOrder
+	ih.mu.Unlock()
+
+	sr, err := ih.searchPage(ctx, q, offset, limit, sortOrder)
+	if err != nil {
+		return nil, err
+	}
+	result := &SearchResult{
+		Docs:   hitDocs(sr.Hits, nil),
+		Total:  int(sr.Total),
+		Offset: offset,
+		Took:   sr.Took,
+		Tags:   tagCounts(sr),
+	}
+	if result.Total >= ih.fuzzyMinHits {
+		return result, nil
+	}
+
+	fallbackQuery, approximated, err := parseQuery(queryString, matchFallback)
+	if err != nil || !approximated {
+		return result, nil
+	}
+	fsr, err := ih.searchPage(ctx, fallbackQuery, offset, limit, scoreFirst(sortOrder))
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		// the exact result is still good
+		return result, nil
+	}
+	if int(fsr.Total) <= result.Total {
+		return result, nil
+	}
+
+	modes, err := ih.matchModes(ctx, fsr.Hits, q, queryString)
+	if err != nil && ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return &SearchResult{
+		Docs:     hitDocs(fsr.Hits, modes),
+		Total:    int(fsr.Total),
+		Offset:   offset,
+		Took:     sr.Took + fsr.Took,
+		Tags:     tagCounts(fsr),
+		Fallback: true,
+	}, nil
+}
+
+// searchPage runs q for limit hits starting at offset, a limit of 0 or less returns every hit
+func (ih *IndexHandler) searchPage(ctx context.Context, q query.Query, offset, limit int, sortOrder search.SortOrder) (*bleve.SearchResult, error) {
+	if limit <= 0 {
+		count, err := ih.count(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		limit = count - offset
+		if limit <= 0 {
+			// bleve treats a size of 0 as a count only request
+			limit = 1
+		}
+	}
+
 	search := &bleve.SearchRequest{
-		Query:     query,
-		Size:      100,
-		From:      0,
+		Query:     q,
+		Size:      limit,
+		From:      offset,
 		Explain:   false,
-		Sort:      search.SortOrder{&search.SortScore{Desc: true}},
-		Fields:    []string{"type", "title", "description", "tags"},
-		Highlight: bleve.NewHighlightWithStyle(ansi.Name),
+		Sort:      sortOrder,
+		Fields:    searchFields(),
+		Highlight: bleve.NewHighlightWithStyle(tviewHighlighterName),
+	}
+	search.Highlight.Fields = search.Fields
+	search.AddFacet(tagsFacetName, bleve.NewFacetRequest("tags", tagFacetSize))
+	ih.mu.Lock()
+	sr, err := ih.index.SearchInContext(ctx, search)
+	ih.mu.Unlock()
+	if err != nil && ctx.Err() != nil {
+		log.Debugf("index search cancelled: %v", err)
+		return nil, ctx.Err()
 	}
-	sr, err := ih.index.Search(search)
 	if err != nil {
 		log.Errorf("index search error: %v", err)
-		return nil, ""
+		return nil, err
 	}
+	return sr, nil
+}
 
-	took := strings.ReplaceAll(sr.Took.String(), "µ", "u")
-	stat := fmt.Sprintf("%d matches, showing %d through %d, took %s", sr.Total, sr.Request.From+1, sr.Request.From+len(sr.Hits), took)
+// scoreFirst puts score ahead of sortOrder so boosted exact matches come before prefix and fuzzy ones
+func scoreFirst(sortOrder search.SortOrder) search.SortOrder {
+	if len(sortOrder) > 0 {
+		if _, ok := sortOrder[0].(*search.SortScore); ok {
+			return sortOrder
+		}
+	}
+	return append(search.SortOrder{&search.SortScore{Desc: true}}, sortOrder...)
+}
+
+// matchModes tells for each hit whether the exact query, the prefix query or only the fuzzy query found it
+func (ih *IndexHandler) matchModes(ctx context.Context, hits search.DocumentMatchCollection, exactQuery query.Query, queryString string) (map[string]string, error) {
+	modes := map[string]string{}
+	if len(hits) == 0 {
+		return modes, nil
+	}
+
+	ids := make([]string, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+		modes[hit.ID] = "fuzzy"
+	}
+
+	prefixQuery, _, err := parseQuery(queryString, matchPrefix)
+	if err != nil {
+		return modes, err
+	}
+	// prefix first so exact overwrites it for hits found by both
+	for _, m := range []struct {
+		mode string
+		q    query.Query
+	}{{"prefix", prefixQuery}, {"exact", exactQuery}} {
+		q := bleve.NewConjunctionQuery(bleve.NewDocIDQuery(ids), m.q)
+		ih.mu.Lock()
+		sr, err := ih.index.SearchInContext(ctx, bleve.NewSearchRequestOptions(q, len(ids), 0, false))
+		ih.mu.Unlock()
+		if err != nil {
+			log.Errorf("match mode search error: %v", err)
+			return modes, err
+		}
+		for _, hit := range sr.Hits {
+			modes[hit.ID] = m.mode
+		}
+	}
+	return modes, nil
+}
 
-	docs := make([]MiniDoc, sr.Hits.Len())
-	for ri, hit := range sr.Hits {
+// hitDocs turns hits into docs, when modes is given the mode that found each hit leads its fragments
+func hitDocs(hits search.DocumentMatchCollection, modes map[string]string) []MiniDoc {
+	docs := make([]MiniDoc, hits.Len())
+	for ri, hit := range hits {
 		idparts := strings.Split(hit.ID, ":")
 		v, _ := strconv.Atoi(idparts[1])
 		log.Debugf("found minidoc[%d]", v)
@@ -135,42 +448,204 @@ func (ih *IndexHandler) Search(queryString string) ([]MiniDoc, string) {
 		if tags, ok := hit.Fields["tags"].(string); ok {
 			minidoc.Tags = tags
 		}
+		if created, ok := hit.Fields["created_date"].(string); ok {
+			minidoc.CreatedDate = created
+		}
+		if updated, ok := hit.Fields["updated_date"].(string); ok {
+			minidoc.UpdatedDate = updated
+		}
 
 		log.Debug("# of fragments: " + strconv.Itoa(len(hit.Fragments)))
-		for fieldName, fragments := range hit.Fragments {
-			rv := "[" + fieldName + "[] "
-			for _, fragment := range fragments {
-				// [43m [0m
-				fragment = strings.ReplaceAll(fragment, "[43m", "[yellow]")
-				fragment = strings.ReplaceAll(fragment, "[0m", "[white]")
-				lines := strings.Split(fragment, "\n")
-				for _, line := range lines {
-					rv += fmt.Sprintf("%s ", line)
+		minidoc.FieldFragments = map[string][]string{}
+		fragments := []string{}
+		for _, fieldName := range fragmentFields(hit) {
+			lines := []string{}
+			for _, fragment := range hit.Fragments[fieldName] {
+				lines = append(lines, strings.Join(strings.Fields(fragment), " "))
+			}
+			minidoc.FieldFragments[fieldName] = lines
+			fragments = append(fragments, "["+fieldName+"[] "+strings.Join(lines, " "))
+		}
+		minidoc.Fragments = strings.Join(fragments, " ")
+		for field, terms := range hit.Locations {
+			if field == titleSortField {
+				continue
+			}
+			for term := range terms {
+				if !contains(minidoc.MatchedTerms, term) {
+					minidoc.MatchedTerms = append(minidoc.MatchedTerms, term)
 				}
 			}
-			minidoc.Fragments = rv
+		}
+		sort.Strings(minidoc.MatchedTerms)
+		if mode, ok := modes[hit.ID]; ok {
+			if len(minidoc.Fragments) == 0 {
+				minidoc.Fragments = tview.Escape(minidoc.Title)
+			}
+			minidoc.Fragments = "[darkcyan]" + mode + "[white] " + minidoc.Fragments
 		}
 
 		docs[ri] = minidoc
 		log.Debugf("%s %f %s", hit.ID, hit.Score, hit.Fields["title"])
 	}
-	return docs, stat
+	return docs
 }
 
-func IndexMapping() (*mapping.IndexMappingImpl, error) {
+// fragmentFields are the fields of hit the query matched in search field order, when the query matched none
+// of them, e.g. a date range, the first field with a fragment stands in
+func fragmentFields(hit *search.DocumentMatch) []string {
+	matched := []string{}
+	first := ""
+	for _, field := range searchFields() {
+		if _, ok := hit.Fragments[field]; !ok {
+			continue
+		}
+		// the highlighter also returns the start of fields without a match
+		if len(hit.Locations[field]) > 0 {
+			matched = append(matched, field)
+		} else if len(first) == 0 {
+			first = field
+		}
+	}
+	if len(matched) == 0 && len(first) > 0 {
+		matched = append(matched, first)
+	}
+	return matched
+}
 
-	// a generic reusable mapping for english text
-	englishTextFieldMapping := bleve.NewTextFieldMapping()
-	englishTextFieldMapping.Analyzer = en.AnalyzerName
+// Tags lists every tag with the number of docs carrying it, most used first
+func (ih *IndexHandler) Tags() ([]TagCount, error) {
+	search := bleve.NewSearchRequestOptions(bleve.NewMatchAllQuery(), 0, 0, false)
+	search.AddFacet(tagsFacetName, bleve.NewFacetRequest("tags", maxTags))
 
-	// a generic reusable mapping for keyword text
-	keywordFieldMapping := bleve.NewTextFieldMapping()
-	keywordFieldMapping.Analyzer = keyword.Name
+	ih.mu.Lock()
+	sr, err := ih.index.Search(search)
+	ih.mu.Unlock()
+	if err != nil {
+		log.Errorf("tags facet error: %v", err)
+		return nil, err
+	}
+	return tagCounts(sr), nil
+}
+
+func tagCounts(sr *bleve.SearchResult) []TagCount {
+	tags := []TagCount{}
+	facet, ok := sr.Facets[tagsFacetName]
+	if !ok || facet.Terms == nil {
+		return tags
+	}
+	for _, term := range facet.Terms {
+		tags = append(tags, TagCount{term.Term, term.Count})
+	}
+	return tags
+}
+
+func (ih *IndexHandler) count(ctx context.Context, q query.Query) (int, error) {
+	ih.mu.Lock()
+	defer ih.mu.Unlock()
+
+	sr, err := ih.index.SearchInContext(ctx, bleve.NewSearchRequestOptions(q, 0, 0, false))
+	if err != nil {
+		log.Errorf("index count error: %v", err)
+		return 0, err
+	}
+	return int(sr.Total), nil
+}
+
+const tagAnalyzerName = "tag"
+
+// keywordAnalyzerName keeps a whole field as one lowercase term, for keyword fields and sorting
+const keywordAnalyzerName = "keyword_lowercase"
+
+// titleSortField holds the title of every doctype, see IndexSpec.SortTitle
+const titleSortField = "title_sort"
+
+// IndexSpec declares how a doctype is indexed
+type IndexSpec struct {
+	// Text fields are english analyzed for full text search
+	Text []string
+	// Keyword fields are matched whole apart from case
+	Keyword []string
+	// Bool fields are indexed as booleans, e.g. done
+	Bool []string
+	// Date fields are indexed as datetime on top of the created and updated dates every doc has
+	Date []string
+	// Numeric fields are indexed as numbers, e.g. priority, zero stands for none and is left out
+	Numeric []string
+	// Derived fields are worked out from the doc fields when indexing and matched whole apart from case
+	Derived map[string]func(fields map[string]interface{}) string
+	// SortTitle is the field results are sorted by when sorting by title
+	SortTitle string
+}
+
+// baseSearchFields come back with every search hit
+var baseSearchFields = []string{"type", "title", "description", "tags", "created_date", "updated_date"}
+
+// keywordFields are the keyword and derived fields of spec
+func (spec IndexSpec) keywordFields() []string {
+	fields := append([]string{}, spec.Keyword...)
+	for field := range spec.Derived {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// searchFields are the fields search hits come back with and get highlighted in, across every doctype
+func searchFields() []string {
+	fields := append([]string{}, baseSearchFields...)
+	for _, doctype := range doctypes {
+		spec := indexSpecOf(doctype)
+		for _, field := range append(spec.Text, spec.keywordFields()...) {
+			if !contains(fields, field) {
+				fields = append(fields, field)
+			}
+		}
+	}
+	return fields
+}
 
+// indexedDocument is what gets indexed for doc, its json along with the derived fields of its doctype, numeric
+// fields left at zero are not indexed
+func indexedDocument(doc MiniDoc) interface{} {
+	json := doc.GetJSON()
+	spec := indexSpecOf(doc.GetType())
+	jsonMap, ok := json.(map[string]interface{})
+	if !ok || len(spec.Derived) == 0 && len(spec.Numeric) == 0 {
+		return json
+	}
+	for field, derive := range spec.Derived {
+		jsonMap[field] = derive(jsonMap)
+	}
+	for _, field := range spec.Numeric {
+		if n, ok := jsonMap[field].(float64); ok && n == 0 {
+			delete(jsonMap, field)
+		}
+	}
+	return jsonMap
+}
+
+func IndexMapping() (*mapping.IndexMappingImpl, error) {
 	indexMapping := bleve.NewIndexMapping()
+	// tags are space separated, each one is kept as is apart from case
+	err := indexMapping.AddCustomAnalyzer(tagAnalyzerName, map[string]interface{}{
+		"type":          custom.Name,
+		"tokenizer":     whitespace.Name,
+		"token_filters": []string{lowercase.Name},
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = indexMapping.AddCustomAnalyzer(keywordAnalyzerName, map[string]interface{}{
+		"type":          custom.Name,
+		"tokenizer":     single.Name,
+		"token_filters": []string{lowercase.Name},
+	})
+	if err != nil {
+		return nil, err
+	}
 	for _, doctype := range doctypes {
-		documentMapping := DocumentMapping(indexedFields[doctype], excludedFields[doctype])
-		indexMapping.AddDocumentMapping(doctype, documentMapping)
+		indexMapping.AddDocumentMapping(doctype, DocumentMapping(indexSpecOf(doctype)))
 	}
 	indexMapping.TypeField = "type"
 	indexMapping.DefaultAnalyzer = "en"
@@ -178,18 +653,53 @@ func IndexMapping() (*mapping.IndexMappingImpl, error) {
 	return indexMapping, nil
 }
 
-func DocumentMapping(indexedFields []string, excludedFields []string) *mapping.DocumentMapping {
+// DocumentMapping maps the fields spec declares, the rest of the doc is indexed as english text
+func DocumentMapping(spec IndexSpec) *mapping.DocumentMapping {
 	englishTextFieldMapping := bleve.NewTextFieldMapping()
 	englishTextFieldMapping.Analyzer = en.AnalyzerName
 
 	documentMapping := bleve.NewDocumentMapping()
-	for _, f := range indexedFields {
+	for _, f := range spec.Text {
 		documentMapping.AddFieldMappingsAt(f, englishTextFieldMapping)
 	}
 
-	disabledFieldMapping := bleve.NewDocumentDisabledMapping()
-	for _, f := range excludedFields {
-		documentMapping.AddSubDocumentMapping(f, disabledFieldMapping)
+	keywordFieldMapping := bleve.NewTextFieldMapping()
+	keywordFieldMapping.Analyzer = keywordAnalyzerName
+	for _, f := range spec.keywordFields() {
+		documentMapping.AddFieldMappingsAt(f, keywordFieldMapping)
+	}
+
+	if len(spec.SortTitle) > 0 {
+		titleSortFieldMapping := bleve.NewTextFieldMapping()
+		titleSortFieldMapping.Analyzer = keywordAnalyzerName
+		titleSortFieldMapping.Name = titleSortField
+		titleSortFieldMapping.Store = false
+		titleSortFieldMapping.IncludeInAll = false
+		documentMapping.AddFieldMappingsAt(spec.SortTitle, titleSortFieldMapping)
+	}
+
+	// tags as keywords for facets and tag: filters, tags_text keeps them in stemmed full text search
+	tagFieldMapping := bleve.NewTextFieldMapping()
+	tagFieldMapping.Analyzer = tagAnalyzerName
+	tagTextFieldMapping := bleve.NewTextFieldMapping()
+	tagTextFieldMapping.Analyzer = en.AnalyzerName
+	tagTextFieldMapping.Name = "tags_text"
+	tagTextFieldMapping.Store = false
+	documentMapping.AddFieldMappingsAt("tags", tagFieldMapping, tagTextFieldMapping)
+
+	dateTimeFieldMapping := bleve.NewDateTimeFieldMapping()
+	for _, f := range append(dateFields, spec.Date...) {
+		documentMapping.AddFieldMappingsAt(f, dateTimeFieldMapping)
+	}
+
+	booleanFieldMapping := bleve.NewBooleanFieldMapping()
+	for _, f := range spec.Bool {
+		documentMapping.AddFieldMappingsAt(f, booleanFieldMapping)
+	}
+
+	numericFieldMapping := bleve.NewNumericFieldMapping()
+	for _, f := range spec.Numeric {
+		documentMapping.AddFieldMappingsAt(f, numericFieldMapping)
 	}
 
 	return documentMapping
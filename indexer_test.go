@@ -1,12 +1,18 @@
 package minidoc
 
 import (
+	"github.com/spf13/viper"
+	"io/ioutil"
+	"os"
+	"strings"
 	"testing"
 )
 
 func TestIndexHandler_Index(t *testing.T) {
 	db := NewBucketHandler()
+	defer db.Close()
 	indexer := NewIndexHandler()
+	defer indexer.Close()
 	doc := GetTestUrlMiniDoc()
 	db.Write(doc)
 
@@ -17,21 +23,25 @@ func TestIndexHandler_Index(t *testing.T) {
 
 func TestIndexHandler_Search(t *testing.T) {
 	db := NewBucketHandler()
+	defer db.Close()
 	indexer := NewIndexHandler()
+	defer indexer.Close()
 	doc := GetTestUrlMiniDoc()
 	db.Write(doc)
 	indexer.Index(doc)
 
-	docs, _ := indexer.Search("baz")
+	result, _ := indexer.Search("baz", 0, 0)
 
-	if len(docs) == 0 {
+	if len(result.Docs) == 0 {
 		t.Fail()
 	}
 }
 
 func TestIndexHandler_Delete(t *testing.T) {
 	db := NewBucketHandler()
+	defer db.Close()
 	indexer := NewIndexHandler()
+	defer indexer.Close()
 	doc := GetTestUrlMiniDoc()
 	db.Write(doc)
 
@@ -81,3 +91,239 @@ func GetTestTodoMiniDoc() *ToDoDoc {
 	}
 	return doc
 }
+
+func TestIndexHandler_SetSortOrder(t *testing.T) {
+	indexer := NewIndexHandler(WithIndexHandlerInMemory())
+	defer indexer.Close()
+
+	older := GetTestNoteMiniDoc()
+	older.ID = 1
+	older.UpdatedDate = "2020-01-01 10:00:00"
+	newer := GetTestNoteMiniDoc()
+	newer.ID = 2
+	newer.UpdatedDate = "2020-02-01 10:00:00"
+	indexer.IndexAll([]MiniDoc{older, newer})
+
+	indexer.SetSortOrder("-updated_date")
+	result, _ := indexer.Search("baz", 0, 0)
+	docs := result.Docs
+	if len(docs) != 2 || docs[0].GetID() != newer.ID {
+		t.Logf("expected newest first but got %v", docs)
+		t.Fail()
+	}
+
+	indexer.SetSortOrder("updated_date")
+	result, _ = indexer.Search("baz", 0, 0)
+	docs = result.Docs
+	if len(docs) != 2 || docs[0].GetID() != older.ID {
+		t.Logf("expected oldest first but got %v", docs)
+		t.Fail()
+	}
+}
+
+func TestIndexHandler_Search_Paging(t *testing.T) {
+	indexer := NewIndexHandler(WithIndexHandlerInMemory())
+	defer indexer.Close()
+
+	docs := []MiniDoc{}
+	for i := 1; i <= 250; i++ {
+		doc := GetTestNoteMiniDoc()
+		doc.ID = uint32(i)
+		docs = append(docs, doc)
+	}
+	indexer.IndexAll(docs)
+
+	result, _ := indexer.Search("foo", 200, 100)
+	if len(result.Docs) != 50 || result.Total != 250 || result.HasMore() {
+		t.Logf("expected last page of 50 docs but got %d of %d", len(result.Docs), result.Total)
+		t.Fail()
+	}
+
+	result, _ = indexer.Search("foo", 0, 0)
+	if len(result.Docs) != 250 {
+		t.Logf("expected every doc without a limit but got %d", len(result.Docs))
+		t.Fail()
+	}
+}
+
+func TestIndexHandler_Tags(t *testing.T) {
+	indexer := NewIndexHandler(WithIndexHandlerInMemory())
+	defer indexer.Close()
+
+	note := GetTestNoteMiniDoc()
+	note.ID = 1
+	note.Tags = "golang Tutorials"
+	todo := GetTestTodoMiniDoc()
+	todo.ID = 2
+	todo.Tags = "golang"
+	indexer.IndexAll([]MiniDoc{note, todo})
+
+	tags, err := indexer.Tags()
+	if err != nil || len(tags) != 2 || tags[0] != (TagCount{"golang", 2}) || tags[1] != (TagCount{"tutorials", 1}) {
+		t.Logf("unexpected tags %v: %v", tags, err)
+		t.Fail()
+	}
+
+	result, _ := indexer.Search("tag:tutorials", 0, 10)
+	if len(result.Docs) != 1 || len(result.Tags) != 2 {
+		t.Logf("expected 1 doc with 2 tag facets but got %d docs and %v", len(result.Docs), result.Tags)
+		t.Fail()
+	}
+}
+
+func TestIndexHandler_Search_FuzzyFallback(t *testing.T) {
+	indexer := NewIndexHandler(WithIndexHandlerInMemory())
+	defer indexer.Close()
+
+	exact := &NoteDoc{BaseDoc: BaseDoc{ID: 1, Type: "note", Title: "kubrenetes typo in the title"}}
+	fuzzy := &NoteDoc{BaseDoc: BaseDoc{ID: 2, Type: "note", Title: "kubernetes operators"}}
+	other := &NoteDoc{BaseDoc: BaseDoc{ID: 3, Type: "note", Title: "golang tips"}}
+	indexer.IndexAll([]MiniDoc{exact, fuzzy, other})
+
+	result, err := indexer.Search("kubrenetes", 0, 10)
+	if err != nil || !result.Fallback || len(result.Docs) != 2 {
+		t.Logf("expected an exact and a fuzzy match but got %v: %v", result, err)
+		t.FailNow()
+	}
+	if result.Docs[0].GetID() != 1 || !strings.Contains(result.Docs[0].GetSearchFragments(), "exact") ||
+		result.Docs[1].GetID() != 2 || !strings.Contains(result.Docs[1].GetSearchFragments(), "fuzzy") {
+		t.Logf("expected the exact match ranked first: %q, %q", result.Docs[0].GetSearchFragments(), result.Docs[1].GetSearchFragments())
+		t.Fail()
+	}
+
+	result, _ = indexer.Search("kub", 0, 10)
+	if len(result.Docs) != 2 || !strings.Contains(result.Docs[0].GetSearchFragments(), "prefix") {
+		t.Logf("expected 2 prefix matches but got %v", result.Docs)
+		t.Fail()
+	}
+
+	result, _ = indexer.Search("kubrenetes -operators", 0, 10)
+	if len(result.Docs) != 1 {
+		t.Logf("expected the excluded term to stay exact but got %v", result.Docs)
+		t.Fail()
+	}
+
+	strict := NewIndexHandler(WithIndexHandlerInMemory(), WithIndexHandlerFuzzyFallback(0))
+	defer strict.Close()
+	strict.IndexAll([]MiniDoc{exact, fuzzy, other})
+	result, _ = strict.Search("kubrenetes", 0, 10)
+	if result.Fallback || len(result.Docs) != 1 {
+		t.Logf("expected exact search only but got %v", result.Docs)
+		t.Fail()
+	}
+}
+
+func TestSaveSortOption(t *testing.T) {
+	dir, err := ioutil.TempDir("", "minidoc")
+	if err != nil {
+		t.Logf("creating temp dir: %v", err)
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+
+	path := dir + "/.minidoc.yml"
+	ioutil.WriteFile(path, []byte("search_sort: score\nsearch_page_size: 50\n"), 0644)
+	cfg := viper.New()
+	cfg.SetConfigFile(path)
+	cfg.ReadInConfig()
+
+	if err := saveSortOption(cfg, "updated"); err != nil {
+		t.Logf("saving sort option: %v", err)
+		t.FailNow()
+	}
+
+	saved := viper.New()
+	saved.SetConfigFile(path)
+	saved.ReadInConfig()
+	if saved.GetString("search_sort") != "updated" || saved.GetInt("search_page_size") != 50 {
+		t.Logf("expected the updated sort option saved next to the other settings but got %v", saved.AllSettings())
+		t.Fail()
+	}
+}
+
+func TestIndexHandler_SortByTitle(t *testing.T) {
+	indexer := NewIndexHandler(WithIndexHandlerInMemory(), WithIndexHandlerSortOrder(titleSortField))
+	defer indexer.Close()
+
+	zebra := &NoteDoc{BaseDoc: BaseDoc{ID: 1, Type: "note", Title: "Zebra crossing", Tags: "sorted"}}
+	apple := &NoteDoc{BaseDoc: BaseDoc{ID: 2, Type: "note", Title: "apple pie", Tags: "sorted", CreatedDate: "2020-01-15 10:00:00"}}
+	mango := &ToDoDoc{BaseDoc: BaseDoc{ID: 3, Type: "todo", Tags: "sorted"}, Task: "Mango smoothie"}
+	indexer.IndexAll([]MiniDoc{zebra, apple, mango})
+
+	result, _ := indexer.Search("tag:sorted", 0, 0)
+	docs := result.Docs
+	if len(docs) != 3 || docs[0].GetID() != apple.ID || docs[1].GetID() != mango.ID || docs[2].GetID() != zebra.ID {
+		t.Logf("expected docs sorted by title regardless of case and doctype but got %v", docs)
+		t.Fail()
+	}
+	if dateOnly(docs[0].GetCreatedDate()) != "2020-01-15" {
+		t.Log("expected the created date to come with search hits")
+		t.Fail()
+	}
+}
+
+func TestIndexHandler_IndexSpecs(t *testing.T) {
+	indexer := NewIndexHandler(WithIndexHandlerInMemory())
+	defer indexer.Close()
+
+	url := &URLDoc{BaseDoc: BaseDoc{ID: 1, Type: "url", Title: "go blog"}, URL: "https://blog.golang.org/Go-Modules"}
+	todo := &ToDoDoc{BaseDoc: BaseDoc{ID: 1, Type: "todo"}, Task: "write slides", Detail: "mention the race detector"}
+	shortcut := &ShortcutKeyDoc{BaseDoc: BaseDoc{ID: 1, Type: "shortcut", Title: "command palette"}, ShortCutKey: "cmd-shift-p"}
+	indexer.IndexAll([]MiniDoc{url, todo, shortcut})
+
+	tests := map[string]string{
+		"host:blog.golang.org": "url:1",
+		"path:/go-modules":     "url:1",
+		"path:/go*":            "url:1",
+		"race":                 "todo:1",
+		"palette":              "shortcut:1",
+	}
+	for q, expected := range tests {
+		result, err := indexer.Search(q, 0, 0)
+		if err != nil || len(result.Docs) != 1 || result.Docs[0].GetIDString() != expected {
+			t.Logf("%s: expected %s but found %v: %v", q, expected, result, err)
+			t.Fail()
+		}
+	}
+
+	result, _ := indexer.Search("race", 0, 0)
+	if len(result.Docs) == 1 && !strings.Contains(result.Docs[0].GetSearchFragments(), "detail") {
+		t.Logf("expected the detail field highlighted but got %q", result.Docs[0].GetSearchFragments())
+		t.Fail()
+	}
+}
+
+func TestIndexHandler_Search_Fragments(t *testing.T) {
+	indexer := NewIndexHandler(WithIndexHandlerInMemory())
+	defer indexer.Close()
+
+	note := &NoteDoc{BaseDoc: BaseDoc{ID: 1, Type: "note", Title: "race notes"}, Note: "racing goroutines, see [note:2]"}
+	indexer.Index(note)
+
+	result, err := indexer.Search("race", 0, 0)
+	if err != nil || len(result.Docs) != 1 {
+		t.Logf("expected 1 doc but got %v: %v", result, err)
+		t.FailNow()
+	}
+	doc := result.Docs[0]
+	fragments := doc.GetFieldFragments()
+	if len(fragments) != 2 || fragments["title"][0] != "[yellow]race[white] notes" ||
+		fragments["note"][0] != "[yellow]racing[white] goroutines, see [note:2[]" {
+		t.Logf("unexpected field fragments %q", fragments)
+		t.Fail()
+	}
+	if doc.GetSearchFragments() != "[title[] [yellow]race[white] notes [note[] [yellow]racing[white] goroutines, see [note:2[]" {
+		t.Logf("unexpected fragments %q", doc.GetSearchFragments())
+		t.Fail()
+	}
+	if len(doc.GetMatchedTerms()) != 1 || doc.GetMatchedTerms()[0] != "race" {
+		t.Logf("unexpected matched terms %v", doc.GetMatchedTerms())
+		t.Fail()
+	}
+
+	matched := map[string]bool{"race": true}
+	if highlightMatched("Racing,", matched) != "[yellow]Racing,[darkcyan]" || highlightMatched("goroutines", matched) != "goroutines" {
+		t.Log("expected only words matching a term highlighted")
+		t.Fail()
+	}
+}
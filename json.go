@@ -153,23 +153,13 @@ func MiniDocFrom(jsonMap interface{}) (MiniDoc, error) {
 	return doc, nil
 }
 
+// NewDoc returns an empty doc of a registered doctype
 func NewDoc(doctype string) (MiniDoc, error) {
-	var doc MiniDoc
-	switch doctype {
-	case "note":
-		doc = &NoteDoc{}
-		doc.SetType("note")
-	case "url":
-		doc = &URLDoc{}
-		doc.SetType("url")
-	case "todo":
-		doc = &ToDoDoc{}
-		doc.SetType("todo")
-	case "shortcut":
-		doc = &ShortcutKeyDoc{}
-		doc.SetType("shortcut")
-	default:
+	dt, found := docTypeNamed(doctype)
+	if !found {
 		return nil, fmt.Errorf("doctype %s not handled", doctype)
 	}
+	doc := dt.New()
+	doc.SetType(doctype)
 	return doc, nil
 }
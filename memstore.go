@@ -0,0 +1,160 @@
+package minidoc
+
+import (
+	"bytes"
+	"sort"
+	"sync"
+)
+
+// MemStore is a Store kept entirely in memory, useful for tools and tests that should not touch the filesystem
+type MemStore struct {
+	mu      sync.RWMutex
+	buckets map[string]map[string][]byte
+}
+
+func NewMemStore() *MemStore {
+	return &MemStore{
+		buckets: map[string]map[string][]byte{},
+	}
+}
+
+func (ms *MemStore) Close() error {
+	return nil
+}
+
+// Update runs fn against a copy of the buckets and only keeps the copy when fn succeeds
+func (ms *MemStore) Update(fn func(tx *BucketTx) error) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	tx := &memTx{buckets: copyBuckets(ms.buckets)}
+	if err := fn(NewBucketTx(tx)); err != nil {
+		return err
+	}
+	ms.buckets = tx.buckets
+	return nil
+}
+
+func (ms *MemStore) View(fn func(tx *BucketTx) error) error {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	return fn(NewBucketTx(&memTx{buckets: ms.buckets, readOnly: true}))
+}
+
+func (ms *MemStore) Write(doc MiniDoc) (uint32, error) {
+	var id uint32
+	err := ms.Update(func(tx *BucketTx) error {
+		var err error
+		id, err = tx.Write(doc)
+		return err
+	})
+	return id, err
+}
+
+func (ms *MemStore) ReadAll(doctype string) ([]MiniDoc, error) {
+	var docs []MiniDoc
+	err := ms.View(func(tx *BucketTx) error {
+		var err error
+		docs, err = tx.ReadAll(doctype)
+		return err
+	})
+	return docs, err
+}
+
+func (ms *MemStore) Read(key uint32, doctype string) (MiniDoc, error) {
+	var doc MiniDoc
+	err := ms.View(func(tx *BucketTx) error {
+		var err error
+		doc, err = tx.Read(key, doctype)
+		return err
+	})
+	return doc, err
+}
+
+func (ms *MemStore) Delete(doc MiniDoc) error {
+	return ms.Update(func(tx *BucketTx) error {
+		return tx.Delete(doc)
+	})
+}
+
+type memTx struct {
+	buckets  map[string]map[string][]byte
+	readOnly bool
+}
+
+func (m *memTx) Get(bucket string, key []byte) []byte {
+	return m.buckets[bucket][string(key)]
+}
+
+func (m *memTx) Put(bucket string, key, value []byte) error {
+	if m.readOnly {
+		return ErrorTxNotWritable
+	}
+	if _, found := m.buckets[bucket]; !found {
+		m.buckets[bucket] = map[string][]byte{}
+	}
+	v := make([]byte, len(value))
+	copy(v, value)
+	m.buckets[bucket][string(key)] = v
+	return nil
+}
+
+func (m *memTx) Delete(bucket string, key []byte) error {
+	if m.readOnly {
+		return ErrorTxNotWritable
+	}
+	delete(m.buckets[bucket], string(key))
+	return nil
+}
+
+// ForEach visits keys in byte order the same way bolt does
+func (m *memTx) ForEach(bucket string, fn func(k, v []byte) error) error {
+	items := m.buckets[bucket]
+	keys := make([]string, 0, len(items))
+	for k := range items {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if err := fn([]byte(k), items[k]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyBuckets(buckets map[string]map[string][]byte) map[string]map[string][]byte {
+	copied := make(map[string]map[string][]byte, len(buckets))
+	for name, items := range buckets {
+		copiedItems := make(map[string][]byte, len(items))
+		for k, v := range items {
+			copiedItems[k] = v
+		}
+		copied[name] = copiedItems
+	}
+	return copied
+}
+
+func (m *memTx) ForEachPrefix(bucket string, prefix []byte, fn func(k, v []byte) error) error {
+	return m.ForEach(bucket, func(k, v []byte) error {
+		if !bytes.HasPrefix(k, prefix) {
+			return nil
+		}
+		return fn(k, v)
+	})
+}
+
+func (m *memTx) LastWithPrefix(bucket string, prefix []byte) ([]byte, []byte) {
+	var last []byte
+	for k := range m.buckets[bucket] {
+		if bytes.HasPrefix([]byte(k), prefix) && (last == nil || k > string(last)) {
+			last = []byte(k)
+		}
+	}
+	if last == nil {
+		return nil, nil
+	}
+	return last, m.buckets[bucket][string(last)]
+}
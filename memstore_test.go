@@ -0,0 +1,63 @@
+package minidoc
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMemStore_WriteRead(t *testing.T) {
+	db := NewMemStore()
+	doc := GetTestNoteMiniDoc()
+	ID, err := db.Write(doc)
+	if err != nil || ID != 1 {
+		t.Logf("id %d: %v", ID, err)
+		t.Fail()
+	}
+
+	doc2, err := db.Read(ID, doc.GetType())
+	if err != nil || doc2.GetTitle() != doc.GetTitle() {
+		t.Log(err)
+		t.Fail()
+	}
+
+	docs, err := db.ReadAll(doc.GetType())
+	if err != nil || len(docs) != 1 {
+		t.Log(err)
+		t.Fail()
+	}
+}
+
+func TestMemStore_Update_Rollback(t *testing.T) {
+	db := NewMemStore()
+
+	doc := GetTestNoteMiniDoc()
+	err := db.Update(func(tx *BucketTx) error {
+		if _, err := tx.Write(doc); err != nil {
+			return err
+		}
+		return fmt.Errorf("abort")
+	})
+	if err == nil {
+		t.Log("we should get the abort error back")
+		t.Fail()
+	}
+
+	_, err = db.Read(doc.GetID(), doc.GetType())
+	if err == nil {
+		t.Log("write should have been rolled back")
+		t.Fail()
+	}
+}
+
+func TestMemStore_View_ReadOnly(t *testing.T) {
+	db := NewMemStore()
+
+	err := db.View(func(tx *BucketTx) error {
+		_, err := tx.Write(GetTestNoteMiniDoc())
+		return err
+	})
+	if err != ErrorTxNotWritable {
+		t.Logf("expected not writable error but got %v", err)
+		t.Fail()
+	}
+}
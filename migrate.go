@@ -0,0 +1,196 @@
+package minidoc
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+const metaBucketName = "_meta"
+
+const schemaVersionField = "schema_version"
+
+// Migration upgrades a single stored record of doctype to Version
+type Migration struct {
+	Version     int
+	Description string
+	Migrate     func(doctype string, record map[string]interface{}) error
+}
+
+var migrations = []Migration{
+	{
+		Version:     1,
+		Description: "backfill updated_date from created_date",
+		Migrate: func(doctype string, record map[string]interface{}) error {
+			if updated, ok := record["updated_date"].(string); !ok || len(updated) == 0 {
+				record["updated_date"] = record["created_date"]
+			}
+			return nil
+		},
+	},
+}
+
+// RegisterMigration adds a migration, versions have to be unique
+func RegisterMigration(m Migration) error {
+	for _, existing := range migrations {
+		if existing.Version == m.Version {
+			return fmt.Errorf("migration version %d already registered", m.Version)
+		}
+	}
+	migrations = append(migrations, m)
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return nil
+}
+
+// CurrentSchemaVersion is the version every record is upgraded to
+func CurrentSchemaVersion() int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].Version
+}
+
+// Backuper is implemented by stores that can copy themselves before a migration
+type Backuper interface {
+	Backup(suffix string) (string, error)
+}
+
+// MigrateSchema upgrades every stored record older than CurrentSchemaVersion, the store is backed up first
+func MigrateSchema(store Store) (int, error) {
+	var version int
+	outdated := 0
+	err := store.View(func(tx *BucketTx) error {
+		version = tx.SchemaVersion()
+		if version >= CurrentSchemaVersion() {
+			return nil
+		}
+		return tx.forEachRecord(func(doctype string, k []byte, record map[string]interface{}) error {
+			if recordSchemaVersion(record) < CurrentSchemaVersion() {
+				outdated++
+			}
+			return nil
+		})
+	})
+	if err != nil || version >= CurrentSchemaVersion() {
+		return 0, err
+	}
+
+	if backuper, ok := store.(Backuper); ok && outdated > 0 {
+		path, err := backuper.Backup(fmt.Sprintf("v%d-%s", version, time.Now().Format("20060102150405")))
+		if err != nil {
+			log.Errorf("backing up before migration: %v", err)
+			return 0, err
+		}
+		log.Infof("backed up store to %s before migrating to schema version %d", path, CurrentSchemaVersion())
+	}
+
+	migrated := 0
+	err = store.Update(func(tx *BucketTx) error {
+		type change struct {
+			doctype string
+			key     []byte
+			data    []byte
+		}
+		changes := []change{}
+		err := tx.forEachRecord(func(doctype string, k []byte, record map[string]interface{}) error {
+			changed, err := migrateRecord(doctype, record)
+			if err != nil || !changed {
+				return err
+			}
+			data, err := json.Marshal(record)
+			if err != nil {
+				return err
+			}
+			changes = append(changes, change{doctype, append([]byte{}, k...), data})
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, c := range changes {
+			if err := tx.kv.Put(c.doctype, c.key, c.data); err != nil {
+				return err
+			}
+		}
+		migrated = len(changes)
+
+		return tx.SetSchemaVersion(CurrentSchemaVersion())
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return migrated, nil
+}
+
+// migrateRecord runs every migration newer than the version stamped on record
+func migrateRecord(doctype string, record map[string]interface{}) (bool, error) {
+	from := recordSchemaVersion(record)
+	changed := false
+	for _, m := range migrations {
+		if m.Version <= from {
+			continue
+		}
+		if err := m.Migrate(doctype, record); err != nil {
+			log.Errorf("migrating %s record to version %d: %v", doctype, m.Version, err)
+			return false, err
+		}
+		record[schemaVersionField] = m.Version
+		changed = true
+	}
+	return changed, nil
+}
+
+// migrateData upgrades the json of a record kept outside the doctype buckets, e.g. a revision or a trashed doc
+func migrateData(doctype string, data []byte) ([]byte, error) {
+	var record map[string]interface{}
+	if err := json.Unmarshal(data, &record); err != nil {
+		log.Errorf("error while unmarshalling %s record: %v", doctype, err)
+		return nil, err
+	}
+	changed, err := migrateRecord(doctype, record)
+	if err != nil || !changed {
+		return data, err
+	}
+	return json.Marshal(record)
+}
+
+func recordSchemaVersion(record map[string]interface{}) int {
+	v, _ := record[schemaVersionField].(float64)
+	return int(v)
+}
+
+// SchemaVersion returns the schema version recorded in the metadata bucket
+func (tx *BucketTx) SchemaVersion() int {
+	v := tx.kv.Get(metaBucketName, []byte(schemaVersionField))
+	if len(v) == 0 {
+		return 0
+	}
+	return int(toUint32(v))
+}
+
+func (tx *BucketTx) SetSchemaVersion(version int) error {
+	return tx.kv.Put(metaBucketName, []byte(schemaVersionField), toBytes(uint32(version)))
+}
+
+// forEachRecord visits the raw json of every stored doc
+func (tx *BucketTx) forEachRecord(fn func(doctype string, k []byte, record map[string]interface{}) error) error {
+	for _, doctype := range doctypes {
+		err := tx.kv.ForEach(doctype, func(k, v []byte) error {
+			var record map[string]interface{}
+			if err := json.Unmarshal(v, &record); err != nil {
+				log.Errorf("error while unmarshalling %s record: %v", doctype, err)
+				return err
+			}
+			return fn(doctype, k, record)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
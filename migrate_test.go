@@ -0,0 +1,80 @@
+package minidoc
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestMigrateSchema(t *testing.T) {
+	db := NewMemStore()
+
+	// a record written before schema versions existed
+	legacy := []byte(`{"id":1,"type":"note","title":"legacy","note":"old note","tags":"","created_date":"2020-01-01 10:00:00"}`)
+	db.Update(func(tx *BucketTx) error {
+		return tx.kv.Put("note", toBytes(1), legacy)
+	})
+
+	migrated, err := MigrateSchema(db)
+	if err != nil || migrated != 1 {
+		t.Logf("migrated %d: %v", migrated, err)
+		t.Fail()
+	}
+
+	var record map[string]interface{}
+	db.View(func(tx *BucketTx) error {
+		if tx.SchemaVersion() != CurrentSchemaVersion() {
+			t.Logf("expected schema version %d but got %d", CurrentSchemaVersion(), tx.SchemaVersion())
+			t.Fail()
+		}
+		return json.Unmarshal(tx.kv.Get("note", toBytes(1)), &record)
+	})
+	if recordSchemaVersion(record) != CurrentSchemaVersion() {
+		t.Log("record should be stamped with the current schema version")
+		t.Fail()
+	}
+	if record["updated_date"] != "2020-01-01 10:00:00" {
+		t.Logf("updated date should be backfilled but got %v", record["updated_date"])
+		t.Fail()
+	}
+
+	migrated, _ = MigrateSchema(db)
+	if migrated != 0 {
+		t.Log("migrations should only run once")
+		t.Fail()
+	}
+}
+
+func TestHistory_MigratesRevisions(t *testing.T) {
+	db := NewMemStore()
+
+	// a revision kept before schema versions existed
+	legacy := []byte(`{"id":1,"type":"note","title":"legacy","note":"old note","tags":"","created_date":"2020-01-01 10:00:00"}`)
+	db.Update(func(tx *BucketTx) error {
+		return tx.kv.Put(historyBucketName("note"), revisionKey(1, 1), legacy)
+	})
+
+	var revisions []Revision
+	err := db.View(func(tx *BucketTx) error {
+		var err error
+		revisions, err = tx.History(1, "note")
+		return err
+	})
+	if err != nil || len(revisions) != 1 {
+		t.Logf("expected one revision but got %v: %v", revisions, err)
+		t.FailNow()
+	}
+
+	jh := NewJsonMapWrapper(revisions[0].Doc.GetJSON())
+	if jh.string("updated_date") != "2020-01-01 10:00:00" {
+		t.Logf("updated date of the revision should be backfilled but got %q", jh.string("updated_date"))
+		t.Fail()
+	}
+}
+
+func TestRegisterMigration_DuplicateVersion(t *testing.T) {
+	err := RegisterMigration(Migration{Version: 1})
+	if err == nil {
+		t.Log("duplicate version should be rejected")
+		t.Fail()
+	}
+}
@@ -17,8 +17,13 @@ type MiniDoc interface {
 	SetTags(string)
 	GetSearchFragments() string
 	SetSearchFragments(string)
+	GetFieldFragments() map[string][]string
+	GetMatchedTerms() []string
 	GetJSON() interface{}
+	GetCreatedDate() string
 	SetCreatedDate(string)
+	GetUpdatedDate() string
+	SetUpdatedDate(string)
 	GetDisplayFields() []string
 	GetEditFields() []string
 	IsSelected() bool
@@ -36,6 +41,7 @@ type MiniDoc interface {
 
 type BaseDoc struct {
 	CreatedDate string `json:"created_date"`
+	UpdatedDate string `json:"updated_date"`
 	ID          uint32 `json:"id"`
 	Type        string `json:"type"`
 	Title       string `json:"title"`
@@ -44,6 +50,10 @@ type BaseDoc struct {
 	Fragments   string `json:"-"`
 	Selected    bool   `json:"-"`
 	Toggled     bool   `json:"-"`
+	// FieldFragments are the highlighted fragments of every field a search matched
+	FieldFragments map[string][]string `json:"-"`
+	// MatchedTerms are the indexed terms a search matched, e.g. stemmed words
+	MatchedTerms []string `json:"-"`
 }
 
 func (m *BaseDoc) GetID() uint32 {
@@ -90,14 +100,34 @@ func (m *BaseDoc) SetSearchFragments(fragments string) {
 	m.Fragments = fragments
 }
 
+func (m *BaseDoc) GetFieldFragments() map[string][]string {
+	return m.FieldFragments
+}
+
+func (m *BaseDoc) GetMatchedTerms() []string {
+	return m.MatchedTerms
+}
+
 func (m *BaseDoc) GetJSON() interface{} {
 	return JsonMapFrom(m)
 }
 
+func (m *BaseDoc) GetCreatedDate() string {
+	return m.CreatedDate
+}
+
 func (m *BaseDoc) SetCreatedDate(createdDate string) {
 	m.CreatedDate = createdDate
 }
 
+func (m *BaseDoc) GetUpdatedDate() string {
+	return m.UpdatedDate
+}
+
+func (m *BaseDoc) SetUpdatedDate(updatedDate string) {
+	m.UpdatedDate = updatedDate
+}
+
 func (m *BaseDoc) IsSelected() bool {
 	return m.Selected
 }
@@ -120,22 +150,32 @@ func (m *BaseDoc) GetMarkdown() string {
 	return "### BaseDoc"
 }
 
-func (M *BaseDoc) GetAvailableActions() string {
+func (m *BaseDoc) GetAvailableActions() string {
+	if dt, found := docTypeNamed(m.Type); found && len(dt.Actions) > 0 {
+		return dt.Actions
+	}
 	return "*"
 }
 
 func (m *BaseDoc) GetDisplayFields() []string {
+	if dt, found := docTypeNamed(m.Type); found && len(dt.DisplayFields) > 0 {
+		return dt.DisplayFields
+	}
 	return []string{
 		"id",
 		"type",
 		"title",
 		"created_date",
+		"updated_date",
 		"description",
 		"tags",
 	}
 }
 
 func (m *BaseDoc) GetEditFields() []string {
+	if dt, found := docTypeNamed(m.Type); found && len(dt.EditFields) > 0 {
+		return dt.EditFields
+	}
 	return []string{
 		"title",
 		"description",
@@ -144,6 +184,9 @@ func (m *BaseDoc) GetEditFields() []string {
 }
 
 func (m *BaseDoc) GetViEditFields() []string {
+	if dt, found := docTypeNamed(m.Type); found {
+		return dt.ViEditFields
+	}
 	return []string{}
 }
 
@@ -160,5 +203,6 @@ func (m *BaseDoc) GetToggle() bool {
 }
 
 func (m *BaseDoc) IsTogglable() bool {
-	return m.Type == "todo" || m.Type == "url"
+	dt, found := docTypeNamed(m.Type)
+	return found && len(dt.ToggleField) > 0
 }
@@ -1,24 +1,78 @@
 package minidoc
 
 import (
+	"bufio"
 	"fmt"
+	"github.com/atotto/clipboard"
 	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+	"net/url"
+	"os"
 	"strings"
+	"time"
 )
 
-var doctypes = []string{"url", "note", "todo", "shortcut"}
-
-var indexedFields = map[string][]string{
-	"url":  {"title", "description", "tags"},
-	"note": {"title", "note", "tags"},
-	"todo": {"task", "done", "tags"},
-}
-
-var excludedFields = map[string][]string{
-	"url":  {"url"},
-	"note": {},
-	"todo": {"detail"},
-}
+func init() {
+	mustRegisterDocType(DocType{
+		Name:          "url",
+		New:           func() MiniDoc { return &URLDoc{} },
+		Index:         urlIndexSpec,
+		ToggleField:   "watch_later",
+		DisplayFields: []string{"id", "type", "url", "watch_later", "title", "description", "tags", "created_date", "updated_date"},
+		EditFields:    []string{"url", "watch_later", "title", "description", "tags"},
+		Actions:       "o <- open url in browser | t <- toggle watch later",
+		NewDocKey:     tcell.KeyCtrlU,
+	})
+	mustRegisterDocType(DocType{
+		Name:          "note",
+		New:           func() MiniDoc { return &NoteDoc{} },
+		Index:         noteIndexSpec,
+		DisplayFields: []string{"id", "type", "title", "note", "tags", "created_date", "updated_date"},
+		EditFields:    []string{"title", "note", "tags"},
+		ViEditFields:  []string{"note"},
+		Actions:       "*",
+		NewDocKey:     tcell.KeyCtrlN,
+	})
+	mustRegisterDocType(DocType{
+		Name:          "todo",
+		New:           func() MiniDoc { return &ToDoDoc{} },
+		Index:         todoIndexSpec,
+		ToggleField:   "done",
+		DisplayFields: []string{"id", "type", "task", "detail", "done", "due", "priority", "recurrence", "tags", "created_date", "updated_date"},
+		EditFields:    []string{"task", "done", "due", "priority", "recurrence", "tags"},
+		ViEditFields:  []string{"detail"},
+		Actions:       "t <- toggle done",
+		NewDocKey:     tcell.KeyCtrlT,
+	})
+	mustRegisterDocType(DocType{
+		Name:          "shortcut",
+		New:           func() MiniDoc { return &ShortcutKeyDoc{} },
+		Index:         shortcutIndexSpec,
+		DisplayFields: []string{"id", "type", "title", "shortcut", "tags", "created_date", "updated_date"},
+		EditFields:    []string{"title", "shortcut", "tags"},
+	})
+	mustRegisterDocType(DocType{
+		Name:          "snippet",
+		New:           func() MiniDoc { return &SnippetDoc{} },
+		Index:         snippetIndexSpec,
+		DisplayFields: []string{"id", "type", "title", "language", "snippet", "description", "tags", "created_date", "updated_date"},
+		EditFields:    []string{"title", "language", "description", "tags"},
+		ViEditFields:  []string{"snippet"},
+		Actions:       "y <- copy snippet to clipboard",
+		NewDocKey:     tcell.KeyCtrlS,
+	})
+	mustRegisterDocType(DocType{
+		Name:          "command",
+		New:           func() MiniDoc { return &CommandDoc{} },
+		Index:         commandIndexSpec,
+		DisplayFields: []string{"id", "type", "title", "command", "directory", "parameters", "exit_code", "last_run", "description", "tags", "created_date", "updated_date"},
+		EditFields:    []string{"title", "command", "directory", "description", "tags"},
+		Actions:       "r <- run command",
+	})
+}
+
+// dateFields are indexed as datetime for every doctype
+var dateFields = []string{"created_date", "updated_date"}
 
 // --------------------------------------------------------------------------------
 // URL Doc
@@ -29,6 +83,30 @@ type URLDoc struct {
 	WatchLater bool   `json:"watch_later"`
 }
 
+// urlIndexSpec also indexes the host and path of the url whole so host:github.com and path:/golang* work
+var urlIndexSpec = IndexSpec{
+	Text:      []string{"title", "description", "url"},
+	Bool:      []string{"watch_later"},
+	SortTitle: "title",
+	Derived: map[string]func(fields map[string]interface{}) string{
+		"host": func(fields map[string]interface{}) string { return urlPart(fields["url"], "host") },
+		"path": func(fields map[string]interface{}) string { return urlPart(fields["url"], "path") },
+	},
+}
+
+// urlPart returns the host or the path of rawurl, nothing when rawurl does not parse
+func urlPart(rawurl interface{}, part string) string {
+	str, _ := rawurl.(string)
+	u, err := url.Parse(strings.TrimSpace(str))
+	if err != nil {
+		return ""
+	}
+	if part == "host" {
+		return u.Hostname()
+	}
+	return u.Path
+}
+
 func (d *URLDoc) GetJSON() interface{} {
 	return JsonMapFrom(d)
 }
@@ -50,37 +128,10 @@ func (d *URLDoc) HandleEvent(event *tcell.EventKey) {
 	}
 }
 
-func (d *URLDoc) GetAvailableActions() string {
-	return "o <- open url in browser | t <- toggle watch later"
-}
-
 func (d *URLDoc) GetMarkdown() string {
 	return fmt.Sprintf(`[%s](%s)`, d.Title, d.URL)
 }
 
-func (d *URLDoc) GetDisplayFields() []string {
-	return []string{
-		"id",
-		"type",
-		"url",
-		"watch_later",
-		"title",
-		"description",
-		"tags",
-		"created_date",
-	}
-}
-
-func (d *URLDoc) GetEditFields() []string {
-	return []string{
-		"url",
-		"watch_later",
-		"title",
-		"description",
-		"tags",
-	}
-}
-
 func (d *URLDoc) GetToggleValueAsString() string {
 	if d.WatchLater {
 		return "✓️"
@@ -104,35 +155,13 @@ type NoteDoc struct {
 	Note string `json:"note"`
 }
 
-func (d *NoteDoc) GetJSON() interface{} {
-	return JsonMapFrom(d)
-}
-
-func (d *NoteDoc) GetDisplayFields() []string {
-	return []string{
-		"id",
-		"type",
-		"title",
-		"note",
-		"tags",
-		"created_date",
-	}
-}
-
-func (d *NoteDoc) GetAvailableActions() string {
-	return "*"
+var noteIndexSpec = IndexSpec{
+	Text:      []string{"title", "note"},
+	SortTitle: "title",
 }
 
-func (d *NoteDoc) GetEditFields() []string {
-	return []string{
-		"title",
-		"note",
-		"tags",
-	}
-}
-
-func (d *NoteDoc) GetViEditFields() []string {
-	return []string{"note"}
+func (d *NoteDoc) GetJSON() interface{} {
+	return JsonMapFrom(d)
 }
 
 func (d *NoteDoc) GetMarkdown() string {
@@ -150,34 +179,35 @@ type ToDoDoc struct {
 	Task   string `json:"task"`
 	Detail string `json:"detail"`
 	Done   bool   `json:"done"`
+	// Due is the day the task is due, yyyy-mm-dd, empty for none
+	Due string `json:"due"`
+	// Priority runs from 1, the highest, to 3, 0 for none
+	Priority int `json:"priority"`
+	// Recurrence is one of daily, weekly, monthly or yearly, a recurring task moves to its next due day when done
+	Recurrence string `json:"recurrence"`
 }
 
-func (d *ToDoDoc) GetJSON() interface{} {
-	return JsonMapFrom(d)
+var todoIndexSpec = IndexSpec{
+	Text:      []string{"task", "detail"},
+	Keyword:   []string{"recurrence"},
+	Bool:      []string{"done"},
+	Date:      []string{"due"},
+	Numeric:   []string{"priority"},
+	SortTitle: "task",
 }
 
-func (d *ToDoDoc) GetDisplayFields() []string {
-	return []string{
-		"id",
-		"type",
-		"task",
-		"detail",
-		"done",
-		"tags",
-		"created_date",
-	}
-}
+const lowestPriority = 3
 
-func (d *ToDoDoc) GetEditFields() []string {
-	return []string{
-		"task",
-		"done",
-		"tags",
-	}
+// recurrences tells how far the next due day of a recurring task is
+var recurrences = map[string]func(due time.Time) time.Time{
+	"daily":   func(due time.Time) time.Time { return due.AddDate(0, 0, 1) },
+	"weekly":  func(due time.Time) time.Time { return due.AddDate(0, 0, 7) },
+	"monthly": func(due time.Time) time.Time { return due.AddDate(0, 1, 0) },
+	"yearly":  func(due time.Time) time.Time { return due.AddDate(1, 0, 0) },
 }
 
-func (d *ToDoDoc) GetViEditFields() []string {
-	return []string{"detail"}
+func (d *ToDoDoc) GetJSON() interface{} {
+	return JsonMapFrom(d)
 }
 
 func (d *ToDoDoc) HandleEvent(event *tcell.EventKey) {
@@ -192,10 +222,6 @@ func (d *ToDoDoc) HandleEvent(event *tcell.EventKey) {
 	//}
 }
 
-func (d *ToDoDoc) GetAvailableActions() string {
-	return "t <- toggle done"
-}
-
 func (d *ToDoDoc) GetMarkdown() string {
 	return fmt.Sprintf(`###%s
   %s`, d.Title, d.Task)
@@ -213,9 +239,48 @@ func (d *ToDoDoc) GetToggleValueAsString() string {
 }
 
 func (d *ToDoDoc) SetToggle(toggle bool) {
+	// a recurring task is never done, it comes back on its next due day instead
+	if next, ok := recurrences[d.Recurrence]; ok && toggle && !d.Done {
+		if due, err := time.Parse(queryDateFormat, d.Due); err == nil {
+			d.Due = next(due).Format(queryDateFormat)
+			return
+		}
+	}
 	d.Done = toggle
 }
 
+// Validate checks the due day, priority and recurrence entered in the edit form, the due day may be given as
+// today, tomorrow or yesterday too
+func (d *ToDoDoc) Validate() error {
+	d.Due = strings.TrimSpace(d.Due)
+	if len(d.Due) > 0 {
+		due, err := parseQueryDate(d.Due)
+		if err != nil {
+			return fmt.Errorf("due: %v", err)
+		}
+		d.Due = due.Format(queryDateFormat)
+	}
+	if d.Priority < 0 || d.Priority > lowestPriority {
+		return fmt.Errorf("priority: expected 1 to %d, 0 for none, but got %d", lowestPriority, d.Priority)
+	}
+	d.Recurrence = strings.ToLower(strings.TrimSpace(d.Recurrence))
+	if _, ok := recurrences[d.Recurrence]; len(d.Recurrence) > 0 && !ok {
+		return fmt.Errorf("recurrence: expected daily, weekly, monthly or yearly but got '%s'", d.Recurrence)
+	}
+	if len(d.Recurrence) > 0 && len(d.Due) == 0 {
+		return fmt.Errorf("recurrence: needs a due day")
+	}
+	return nil
+}
+
+// IsOverdue tells whether the task is still open after its due day
+func (d *ToDoDoc) IsOverdue(now time.Time) bool {
+	if d.Done || len(d.Due) == 0 {
+		return false
+	}
+	return d.Due < now.Format(queryDateFormat)
+}
+
 func (d *ToDoDoc) GetToggle() bool {
 	return d.Done
 }
@@ -228,25 +293,207 @@ type ShortcutKeyDoc struct {
 	ShortCutKey string `json:"shortcut"`
 }
 
+var shortcutIndexSpec = IndexSpec{
+	Text:      []string{"title", "shortcut"},
+	SortTitle: "title",
+}
+
 func (d *ShortcutKeyDoc) GetJSON() interface{} {
 	return JsonMapFrom(d)
 }
 
-func (d *ShortcutKeyDoc) GetDisplayFields() []string {
-	return []string{
-		"id",
-		"type",
-		"title",
-		"shortcut",
-		"tags",
-		"created_date",
+// --------------------------------------------------------------------------------
+// Snippet Doc
+// --------------------------------------------------------------------------------
+type SnippetDoc struct {
+	BaseDoc
+	Language string `json:"language"`
+	Snippet  string `json:"snippet"`
+}
+
+var snippetIndexSpec = IndexSpec{
+	Text:      []string{"title", "description", "snippet"},
+	Keyword:   []string{"language"},
+	SortTitle: "title",
+}
+
+// codeDoc is a doc with a field of source code, the preview highlights it and vim edits it as a file of its language
+type codeDoc interface {
+	CodeField() string
+	CodeLanguage() string
+}
+
+func (d *SnippetDoc) GetJSON() interface{} {
+	return JsonMapFrom(d)
+}
+
+func (d *SnippetDoc) CodeField() string {
+	return "snippet"
+}
+
+func (d *SnippetDoc) CodeLanguage() string {
+	return d.Language
+}
+
+func (d *SnippetDoc) HandleEvent(event *tcell.EventKey) {
+	eventKey := event.Key()
+
+	switch eventKey {
+	case tcell.KeyRune:
+		switch event.Rune() {
+		case 'y':
+			if err := clipboard.WriteAll(d.Snippet); err != nil {
+				log.Errorf("copying snippet %s: %v", d.GetIDString(), err)
+			}
+		}
+	}
+}
+
+func (d *SnippetDoc) GetMarkdown() string {
+	return fmt.Sprintf(`## %s
+%s%s
+%s
+%s`, d.Title, "```", strings.ToLower(d.Language), d.Snippet, "```")
+}
+
+// --------------------------------------------------------------------------------
+// Command Doc
+// --------------------------------------------------------------------------------
+type CommandDoc struct {
+	BaseDoc
+	Command   string `json:"command"`
+	Directory string `json:"directory"`
+	// Parameters are the values the {{placeholders}} were last run with, one name=value a line
+	Parameters string `json:"parameters"`
+	ExitCode   int    `json:"exit_code"`
+	LastRun    string `json:"last_run"`
+	search     *Search
+}
+
+var commandIndexSpec = IndexSpec{
+	Text:      []string{"title", "description", "command", "directory"},
+	Date:      []string{"last_run"},
+	SortTitle: "title",
+}
+
+func (d *CommandDoc) GetJSON() interface{} {
+	return JsonMapFrom(d)
+}
+
+func (d *CommandDoc) SetSearch(s *Search) {
+	d.search = s
+}
+
+func (d *CommandDoc) GetTitle() string {
+	if len(d.Title) == 0 {
+		return d.Command
+	}
+	return d.Title
+}
+
+func (d *CommandDoc) HandleEvent(event *tcell.EventKey) {
+	eventKey := event.Key()
+
+	switch eventKey {
+	case tcell.KeyRune:
+		switch event.Rune() {
+		case 'r':
+			d.Run()
+		}
+	}
+}
+
+// Run asks for the values of the placeholders, if there are any, and runs the command with the app suspended
+func (d *CommandDoc) Run() {
+	if d.search == nil {
+		log.Errorf("running %s: no app to suspend", d.GetIDString())
+		return
+	}
+
+	params := commandParameters(d.Command)
+	if len(params) == 0 {
+		d.run(map[string]string{})
+		return
+	}
+
+	app := d.search.App
+	last := parseParameters(d.Parameters)
+	form, input, pages := SingleEntryModalForm("Run "+d.GetTitle(), params[0]+":", last[params[0]], 60, 2*len(params)+5)
+	inputs := []*tview.InputField{input}
+	for i, param := range params[1:] {
+		form.AddInputField(param+":", last[param], 0, nil, nil)
+		if input, ok := form.GetFormItem(i + 1).(*tview.InputField); ok {
+			inputs = append(inputs, input)
+		}
+	}
+
+	form.AddButton("Run", func() {
+		values := map[string]string{}
+		for i, input := range inputs {
+			values[params[i]] = input.GetText()
+		}
+		d.Parameters = formatParameters(params, values)
+		d.run(values)
+		if err := d.search.WriteChanged(d); err != nil {
+			log.Errorf("recording the run of %s: %v", d.GetIDString(), err)
+		}
+		d.search.Preview(DIRECTION_NONE)
+		if err := app.SetRoot(app.Layout, true).Run(); err != nil {
+			panic(err)
+		}
+	})
+	form.AddButton("Cancel", func() {
+		if err := app.SetRoot(app.Layout, true).Run(); err != nil {
+			panic(err)
+		}
+	})
+
+	if err := app.SetRoot(pages, true).Run(); err != nil {
+		panic(err)
+	}
+}
+
+// run runs the command in the terminal and records its exit code and when it ran
+func (d *CommandDoc) run(values map[string]string) {
+	command := expandCommand(d.Command, values)
+	d.search.App.Suspend(func() {
+		fmt.Printf("$ %s\n", command)
+		exitCode, err := RunCommand(command, d.Directory, os.Stdin, os.Stdout, os.Stderr)
+		if err != nil {
+			log.Errorf("running %s: %v", d.GetIDString(), err)
+			fmt.Println(err)
+		}
+		d.ExitCode = exitCode
+		d.LastRun = time.Now().Format(dateTimeFormat)
+
+		fmt.Printf("\nexit code %d, press enter to go back to minidoc", exitCode)
+		bufio.NewReader(os.Stdin).ReadString('\n')
+	})
+}
+
+func (d *CommandDoc) GetMarkdown() string {
+	return fmt.Sprintf(`## %s
+%s
+%s
+%s`, d.GetTitle(), "```sh", d.Command, "```")
+}
+
+// parseParameters reads the name=value lines of CommandDoc.Parameters
+func parseParameters(parameters string) map[string]string {
+	values := map[string]string{}
+	for _, line := range strings.Split(parameters, "\n") {
+		pair := strings.SplitN(line, "=", 2)
+		if len(pair) == 2 {
+			values[strings.TrimSpace(pair[0])] = pair[1]
+		}
 	}
+	return values
 }
 
-func (d *ShortcutKeyDoc) GetEditFields() []string {
-	return []string{
-		"title",
-		"shortcut",
-		"tags",
+func formatParameters(params []string, values map[string]string) string {
+	lines := []string{}
+	for _, param := range params {
+		lines = append(lines, param+"="+values[param])
 	}
+	return strings.Join(lines, "\n")
 }
@@ -50,11 +50,19 @@ func (n *New) CreateAction() {
 	f := n.Form
 	jh := NewJsonMapWrapper(n.json)
 
-	ExtractFieldValues(jh, f)
+	if err := ExtractFieldValues(jh, f); err != nil {
+		n.App.SetStatus("[black:red]" + err.Error() + "[white]")
+		return
+	}
 
 	doc, err := MiniDocFrom(n.json)
 	if err != nil {
 		log.Errorf("MiniDocFrom failed: %v", err)
+		n.App.SetStatus("[black:red]" + err.Error() + "[white]")
+		return
+	}
+	if err := ValidateDoc(doc); err != nil {
+		n.App.SetStatus("[black:red]" + err.Error() + "[white]")
 		return
 	}
 	log.Debugf("minidoc from json: %v", n.json)
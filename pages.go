@@ -22,6 +22,16 @@ type PageItem interface {
 	GetInstance() interface{}
 }
 
+// Refresher is implemented by page items that reload their content whenever they are switched to
+type Refresher interface {
+	Refresh()
+}
+
+// MenuLabeler is implemented by page items whose menu bar label carries more than their title, e.g. a count
+type MenuLabeler interface {
+	MenuLabel() string
+}
+
 // Page object represent a page for Pages
 type PageFunc func() (title string, content tview.Primitive)
 
@@ -33,6 +43,8 @@ type PagesHandler struct {
 	CurrPageIndex int
 	PageItems     []PageItem
 	PrevMenuText  string
+	// contents are the primitives of PageItems, kept so pages can be renumbered when one is removed
+	contents []tview.Primitive
 }
 
 // GotoPageByTitle goes to page with specified title
@@ -73,9 +85,11 @@ func (p *PagesHandler) GoToNextPage() {
 
 // LoadPages loads pages
 func (p *PagesHandler) LoadPages(s *SimpleApp) {
+	p.contents = nil
 	for index, pi := range p.PageItems {
 		pi.SetApp(s)
 		title, primitive := pi.Page()
+		p.contents = append(p.contents, primitive)
 		fmt.Fprintf(p.MenuBar, `["%d"][darkcyan]%s[white][""]  `, index, title)
 		indexStr := strconv.Itoa(index)
 		p.Pages.AddPage(indexStr, primitive, true, index == p.CurrPageIndex)
@@ -89,6 +103,7 @@ func (p *PagesHandler) AddPage(s *SimpleApp, pi PageItem) {
 	p.PageItems = append(p.PageItems, pi)
 	pi.SetApp(s)
 	title, primitive := pi.Page()
+	p.contents = append(p.contents, primitive)
 	log.Debugf("add::previous menu text: %s", p.PrevMenuText)
 	text := fmt.Sprintf(`["%d"][darkcyan]%s[white][""]  `, index, title)
 	log.Debugf("add::new menu text: %s", text)
@@ -111,11 +126,48 @@ func (p *PagesHandler) RemoveLastPage(s *SimpleApp) {
 	title, _ := pi.Page()
 	p.Pages.RemovePage(indexStr)
 	p.PageItems = p.PageItems[:index]
+	p.contents = p.contents[:index]
 	p.CurrPageIndex = index - 1
 	log.Debugf("current index %d", p.CurrPageIndex)
 	delete(p.PageIndex, title)
 }
 
+// RemovePage removes the page called title, the pages after it move up one place in the menu bar
+func (p *PagesHandler) RemovePage(title string) {
+	indexStr, found := p.PageIndex[title]
+	if !found {
+		return
+	}
+	index, _ := strconv.Atoi(indexStr)
+	log.Debugf("remove page %s at index %d", title, index)
+
+	// pages are named by their index so every page from index on is added again under its new index
+	for i := index; i < len(p.PageItems); i++ {
+		p.Pages.RemovePage(strconv.Itoa(i))
+	}
+	p.PageItems = append(p.PageItems[:index], p.PageItems[index+1:]...)
+	p.contents = append(p.contents[:index], p.contents[index+1:]...)
+	for i := index; i < len(p.PageItems); i++ {
+		p.Pages.AddPage(strconv.Itoa(i), p.contents[i], true, false)
+	}
+
+	delete(p.PageIndex, title)
+	for t, s := range p.PageIndex {
+		if i, _ := strconv.Atoi(s); i > index {
+			p.PageIndex[t] = strconv.Itoa(i - 1)
+		}
+	}
+
+	if p.CurrPageIndex < index {
+		p.RefreshMenu()
+		return
+	}
+	if p.CurrPageIndex > 0 {
+		p.CurrPageIndex--
+	}
+	p.highlightAndSwitch()
+}
+
 // UnloadPages unload pages
 func (p *PagesHandler) UnloadPages() {
 	for index := range p.PageItems {
@@ -132,4 +184,30 @@ func (p *PagesHandler) highlightAndSwitch() {
 	log.Debugf("switching to index %d", p.CurrPageIndex)
 	p.MenuBar.Highlight(index).ScrollToHighlight()
 	p.Pages.SwitchToPage(index)
+	if r, ok := p.PageItems[p.CurrPageIndex].(Refresher); ok {
+		r.Refresh()
+	}
+	p.RefreshMenu()
+}
+
+// RefreshMenu rewrites the menu bar so the labels of MenuLabeler page items are up to date
+func (p *PagesHandler) RefreshMenu() {
+	titles := make([]string, len(p.PageItems))
+	for title, indexStr := range p.PageIndex {
+		index, _ := strconv.Atoi(indexStr)
+		if index < len(titles) {
+			titles[index] = title
+		}
+	}
+
+	text := ""
+	for index, pi := range p.PageItems {
+		label := titles[index]
+		if l, ok := pi.(MenuLabeler); ok {
+			label = l.MenuLabel()
+		}
+		text += fmt.Sprintf(`["%d"][darkcyan]%s[white][""]  `, index, label)
+	}
+	p.MenuBar.SetText(text)
+	p.MenuBar.Highlight(strconv.Itoa(p.CurrPageIndex)).ScrollToHighlight()
 }
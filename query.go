@@ -0,0 +1,496 @@
+package minidoc
+
+import (
+	"fmt"
+	"github.com/blevesearch/bleve"
+	"github.com/blevesearch/bleve/analysis"
+	"github.com/blevesearch/bleve/analysis/lang/en"
+	"github.com/blevesearch/bleve/registry"
+	"github.com/blevesearch/bleve/search/query"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// matchMode decides how plain search terms, the ones without a field, are matched
+type matchMode int
+
+const (
+	matchExact matchMode = iota
+	// matchFallback matches terms exactly, by prefix or with typos, exact matches score highest
+	matchFallback
+	// matchPrefix matches terms by prefix only
+	matchPrefix
+)
+
+// exactBoost ranks exact matches above prefix and fuzzy ones in fallback mode
+const exactBoost = 10.0
+
+// fieldAliases are the short field names accepted in the search bar
+var fieldAliases = map[string]string{
+	"tag":     "tags",
+	"created": "created_date",
+	"updated": "updated_date",
+}
+
+const queryDateFormat = "2006-01-02"
+
+type queryToken struct {
+	field   string
+	value   string
+	phrase  bool
+	negated bool
+	or      bool
+}
+
+// ParseQuery turns what is typed in the search bar into a bleve query.
+//
+//	golang tutorial        both words, anywhere
+//	"exact phrase"         words next to each other
+//	tag:golang type:url    field filters, tag is short for tags
+//	tag:lang               lang and every tag under it such as lang/go
+//	done:false             true/false for toggle fields
+//	-draft  -tag:old       exclude
+//	vim OR emacs           either side
+//	gola*                  prefix
+//	created:2020-01-01..2020-02-01  created:>=2020-01-01  updated:<2020-03-01
+//	due:<tomorrow          today, tomorrow and yesterday stand in for dates
+//	priority:1  priority:<=2  priority:1..2
+func ParseQuery(queryString string) (query.Query, error) {
+	q, _, err := parseQuery(queryString, matchExact)
+	return q, err
+}
+
+// parseQuery also tells whether any plain term was matched other than exactly
+func parseQuery(queryString string, mode matchMode) (query.Query, bool, error) {
+	tokens, err := tokenizeQuery(queryString)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(tokens) == 0 {
+		return nil, false, fmt.Errorf("empty query")
+	}
+
+	fieldTypes := queryFieldTypes()
+
+	must := []query.Query{}
+	mustNot := []query.Query{}
+	var disjuncts []query.Query
+	orPending := false
+	approximated := false
+	for i, token := range tokens {
+		if token.or {
+			if i == 0 || i == len(tokens)-1 || disjuncts == nil || orPending {
+				return nil, false, fmt.Errorf("OR needs a term on both sides")
+			}
+			orPending = true
+			continue
+		}
+
+		tokenMode := mode
+		if token.negated {
+			// excluding near misses would hide docs the user never asked to drop
+			tokenMode = matchExact
+		}
+		q, approximate, err := tokenQuery(token, fieldTypes, tokenMode)
+		if err != nil {
+			return nil, false, err
+		}
+		approximated = approximated || approximate
+
+		if token.negated {
+			if orPending {
+				return nil, false, fmt.Errorf("OR cannot be followed by an excluded term")
+			}
+			mustNot = append(mustNot, q)
+			continue
+		}
+
+		if orPending {
+			disjuncts = append(disjuncts, q)
+			orPending = false
+			continue
+		}
+		if disjuncts != nil {
+			must = append(must, disjunctionOf(disjuncts))
+		}
+		disjuncts = []query.Query{q}
+	}
+	if disjuncts != nil {
+		must = append(must, disjunctionOf(disjuncts))
+	}
+
+	if len(mustNot) == 0 && len(must) == 1 {
+		return must[0], approximated, nil
+	}
+
+	bq := bleve.NewBooleanQuery()
+	if len(must) > 0 {
+		bq.AddMust(must...)
+	} else {
+		// only exclusions, start from everything
+		bq.AddMust(bleve.NewMatchAllQuery())
+	}
+	bq.AddMustNot(mustNot...)
+	return bq, approximated, nil
+}
+
+func disjunctionOf(queries []query.Query) query.Query {
+	if len(queries) == 1 {
+		return queries[0]
+	}
+	return bleve.NewDisjunctionQuery(queries...)
+}
+
+func tokenizeQuery(queryString string) ([]queryToken, error) {
+	tokens := []queryToken{}
+	runes := []rune(queryString)
+	for i := 0; i < len(runes); {
+		if runes[i] == ' ' || runes[i] == '\t' {
+			i++
+			continue
+		}
+
+		token := queryToken{}
+		if runes[i] == '-' {
+			token.negated = true
+			i++
+		}
+
+		// field name, up to a colon
+		start := i
+		for i < len(runes) && runes[i] != ' ' && runes[i] != ':' && runes[i] != '"' {
+			i++
+		}
+		// a pasted url such as https://github.com is a plain term rather than a field filter
+		if i < len(runes) && runes[i] == ':' && !strings.HasPrefix(string(runes[i:]), "://") {
+			token.field = strings.ToLower(string(runes[start:i]))
+			if len(token.field) == 0 {
+				return nil, fmt.Errorf("missing field name before ':'")
+			}
+			i++
+		} else {
+			i = start
+		}
+
+		if i < len(runes) && runes[i] == '"' {
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("missing closing quote")
+			}
+			token.value = string(runes[i+1 : end])
+			token.phrase = true
+			i = end + 1
+		} else {
+			start = i
+			for i < len(runes) && runes[i] != ' ' && runes[i] != '\t' {
+				i++
+			}
+			token.value = string(runes[start:i])
+		}
+
+		if len(strings.TrimSpace(token.value)) == 0 {
+			switch {
+			case len(token.field) > 0:
+				return nil, fmt.Errorf("missing value for %s:", token.field)
+			case token.negated && !token.phrase:
+				return nil, fmt.Errorf("missing term after '-'")
+			}
+			continue
+		}
+
+		if token.value == "OR" && len(token.field) == 0 && !token.phrase && !token.negated {
+			token.or = true
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+// tokenQuery builds the query for a single token, plain terms are approximated unless mode is matchExact
+func tokenQuery(token queryToken, fieldTypes map[string]string, mode matchMode) (query.Query, bool, error) {
+	field := token.field
+	if alias, ok := fieldAliases[field]; ok {
+		field = alias
+	}
+
+	fieldType := "text"
+	if len(field) > 0 {
+		t, ok := fieldTypes[field]
+		if !ok {
+			return nil, false, fmt.Errorf("unknown field '%s'", token.field)
+		}
+		fieldType = t
+	}
+
+	switch fieldType {
+	case "bool":
+		switch strings.ToLower(token.value) {
+		case "true", "yes":
+			q := bleve.NewBoolFieldQuery(true)
+			q.SetField(field)
+			return q, false, nil
+		case "false", "no":
+			q := bleve.NewBoolFieldQuery(false)
+			q.SetField(field)
+			return q, false, nil
+		}
+		return nil, false, fmt.Errorf("%s: expects true or false but got '%s'", token.field, token.value)
+	case "date":
+		q, err := dateRangeQuery(field, token.value)
+		return q, false, err
+	case "number":
+		q, err := numericRangeQuery(field, token.value)
+		return q, false, err
+	}
+
+	if token.phrase {
+		q := bleve.NewMatchPhraseQuery(token.value)
+		q.SetField(field)
+		return q, false, nil
+	}
+
+	if strings.HasSuffix(token.value, "*") {
+		prefix := strings.ToLower(strings.TrimRight(token.value, "*"))
+		if len(prefix) == 0 {
+			return nil, false, fmt.Errorf("missing prefix before '*'")
+		}
+		q := bleve.NewPrefixQuery(prefix)
+		q.SetField(field)
+		return q, false, nil
+	}
+
+	if field == "tags" {
+		return tagQuery(token.value), false, nil
+	}
+
+	if len(field) == 0 && mode != matchExact {
+		return approximateQuery(token.value, mode), true, nil
+	}
+
+	q := bleve.NewMatchQuery(token.value)
+	q.SetField(field)
+	return q, false, nil
+}
+
+// approximateQuery matches term by prefix, and in fallback mode exactly or within a typo or two as well
+func approximateQuery(term string, mode matchMode) query.Query {
+	prefix := bleve.NewPrefixQuery(strings.ToLower(term))
+	if mode == matchPrefix {
+		return prefix
+	}
+
+	exact := bleve.NewMatchQuery(term)
+	exact.SetBoost(exactBoost)
+	queries := []query.Query{exact, prefix}
+
+	// fuzzy terms are compared against the stemmed terms in the index
+	stem := stemmed(term)
+	if n := len([]rune(stem)); n >= 3 {
+		fuzzy := bleve.NewFuzzyQuery(stem)
+		fuzzy.SetFuzziness(1)
+		if n > 5 {
+			fuzzy.SetFuzziness(2)
+		}
+		queries = append(queries, fuzzy)
+	}
+	return bleve.NewDisjunctionQuery(queries...)
+}
+
+var (
+	stemmerOnce sync.Once
+	stemmer     *analysis.Analyzer
+)
+
+// stemmed runs term through the english analyzer the text fields are indexed with
+func stemmed(term string) string {
+	stemmerOnce.Do(func() {
+		a, err := registry.NewCache().AnalyzerNamed(en.AnalyzerName)
+		if err != nil {
+			log.Errorf("loading english analyzer: %v", err)
+			return
+		}
+		stemmer = a
+	})
+	lower := strings.ToLower(term)
+	if stemmer == nil {
+		return lower
+	}
+	tokens := stemmer.Analyze([]byte(lower))
+	if len(tokens) == 0 {
+		return lower
+	}
+	return string(tokens[0].Term)
+}
+
+// tagQuery matches tag itself and every tag under it, tag:lang finds lang/go too
+func tagQuery(tag string) query.Query {
+	tag = strings.ToLower(tag)
+	exact := bleve.NewTermQuery(tag)
+	exact.SetField("tags")
+	children := bleve.NewPrefixQuery(tag + tagSeparator)
+	children.SetField("tags")
+	return bleve.NewDisjunctionQuery(exact, children)
+}
+
+// dateRangeQuery handles 2020-01-01, 2020-01-01..2020-02-01, >2020-01-01, >=, < and <=, whole days are inclusive
+func dateRangeQuery(field, value string) (query.Query, error) {
+	day := 24 * time.Hour
+	inclusive := true
+	exclusive := false
+
+	var start, end time.Time
+	var err error
+	switch {
+	case strings.Contains(value, ".."):
+		bounds := strings.SplitN(value, "..", 2)
+		if len(bounds[0]) > 0 {
+			if start, err = parseQueryDate(bounds[0]); err != nil {
+				return nil, err
+			}
+		}
+		if len(bounds[1]) > 0 {
+			if end, err = parseQueryDate(bounds[1]); err != nil {
+				return nil, err
+			}
+			end = end.Add(day)
+		}
+	case strings.HasPrefix(value, ">="):
+		start, err = parseQueryDate(value[2:])
+	case strings.HasPrefix(value, ">"):
+		start, err = parseQueryDate(value[1:])
+		start = start.Add(day)
+	case strings.HasPrefix(value, "<="):
+		end, err = parseQueryDate(value[2:])
+		end = end.Add(day)
+	case strings.HasPrefix(value, "<"):
+		end, err = parseQueryDate(value[1:])
+	default:
+		start, err = parseQueryDate(value)
+		end = start.Add(day)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if start.IsZero() && end.IsZero() {
+		return nil, fmt.Errorf("%s: missing date in '%s'", field, value)
+	}
+
+	q := bleve.NewDateRangeInclusiveQuery(start, end, &inclusive, &exclusive)
+	q.SetField(field)
+	return q, nil
+}
+
+// relativeDates are the days that can be named instead of written out in a query
+var relativeDates = map[string]int{
+	"yesterday": -1,
+	"today":     0,
+	"tomorrow":  1,
+}
+
+func parseQueryDate(value string) (time.Time, error) {
+	if days, ok := relativeDates[strings.ToLower(value)]; ok {
+		// dates are indexed without a zone, so is today
+		today, _ := time.Parse(queryDateFormat, time.Now().Format(queryDateFormat))
+		return today.AddDate(0, 0, days), nil
+	}
+	t, err := time.Parse(queryDateFormat, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date '%s', expected yyyy-mm-dd", value)
+	}
+	return t, nil
+}
+
+// numericRangeQuery handles 2, 1..3, >1, >=, < and <=, both ends of a range are inclusive
+func numericRangeQuery(field, value string) (query.Query, error) {
+	inclusive := true
+	exclusive := false
+
+	var min, max *float64
+	var minInclusive, maxInclusive *bool
+	var err error
+	switch {
+	case strings.Contains(value, ".."):
+		bounds := strings.SplitN(value, "..", 2)
+		if len(bounds[0]) > 0 {
+			if min, err = parseQueryNumber(field, bounds[0]); err != nil {
+				return nil, err
+			}
+		}
+		if len(bounds[1]) > 0 {
+			if max, err = parseQueryNumber(field, bounds[1]); err != nil {
+				return nil, err
+			}
+		}
+		minInclusive, maxInclusive = &inclusive, &inclusive
+	case strings.HasPrefix(value, ">="):
+		min, err = parseQueryNumber(field, value[2:])
+		minInclusive = &inclusive
+	case strings.HasPrefix(value, ">"):
+		min, err = parseQueryNumber(field, value[1:])
+		minInclusive = &exclusive
+	case strings.HasPrefix(value, "<="):
+		max, err = parseQueryNumber(field, value[2:])
+		maxInclusive = &inclusive
+	case strings.HasPrefix(value, "<"):
+		max, err = parseQueryNumber(field, value[1:])
+		maxInclusive = &exclusive
+	default:
+		min, err = parseQueryNumber(field, value)
+		max = min
+		minInclusive, maxInclusive = &inclusive, &inclusive
+	}
+	if err != nil {
+		return nil, err
+	}
+	if min == nil && max == nil {
+		return nil, fmt.Errorf("%s: missing number in '%s'", field, value)
+	}
+
+	q := bleve.NewNumericRangeInclusiveQuery(min, max, minInclusive, maxInclusive)
+	q.SetField(field)
+	return q, nil
+}
+
+func parseQueryNumber(field, value string) (*float64, error) {
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s: expects a number but got '%s'", field, value)
+	}
+	return &n, nil
+}
+
+// queryFieldTypes lists the fields of every doctype as text, bool, date or number
+func queryFieldTypes() map[string]string {
+	fieldTypes := map[string]string{}
+	for _, doctype := range doctypes {
+		doc, err := NewDoc(doctype)
+		if err != nil {
+			continue
+		}
+		for _, field := range doc.GetDisplayFields() {
+			fieldTypes[field] = "text"
+		}
+		spec := indexSpecOf(doctype)
+		for _, field := range spec.keywordFields() {
+			fieldTypes[field] = "text"
+		}
+		for _, field := range spec.Bool {
+			fieldTypes[field] = "bool"
+		}
+		for _, field := range spec.Date {
+			fieldTypes[field] = "date"
+		}
+		for _, field := range spec.Numeric {
+			fieldTypes[field] = "number"
+		}
+	}
+	for _, field := range dateFields {
+		fieldTypes[field] = "date"
+	}
+	return fieldTypes
+}
@@ -0,0 +1,110 @@
+package minidoc
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseQuery_Search(t *testing.T) {
+	indexer := NewIndexHandler(WithIndexHandlerInMemory())
+	defer indexer.Close()
+
+	note := &NoteDoc{BaseDoc: BaseDoc{ID: 1, Type: "note", Title: "golang tips", Tags: "golang", CreatedDate: "2020-01-15 10:00:00"}, Note: "use the race detector"}
+	todo := &ToDoDoc{BaseDoc: BaseDoc{ID: 2, Type: "todo", Tags: "golang", CreatedDate: "2020-02-15 10:00:00"}, Task: "learn generics"}
+	url := &URLDoc{BaseDoc: BaseDoc{ID: 3, Type: "url", Title: "rust book", Tags: "draft", CreatedDate: "2020-03-15 10:00:00"}, URL: "https://doc.rust-lang.org/book"}
+	indexer.IndexAll([]MiniDoc{note, todo, url})
+
+	tests := map[string]int{
+		"tag:golang":                     2,
+		"tag:golang type:note":           1,
+		"done:false":                     1,
+		`"race detector"`:                1,
+		`"detector race"`:                0,
+		"-tag:draft":                     2,
+		"rust OR generics":               2,
+		"gola*":                          2,
+		"created:2020-02-01..2020-03-15": 2,
+		"created:>=2020-02-15":           2,
+		"created:<2020-02-15":            1,
+		"https://doc.rust-lang.org/book": 1,
+	}
+	for q, expected := range tests {
+		result, err := indexer.Search(q, 0, 0)
+		if err != nil || len(result.Docs) != expected {
+			t.Logf("%s: expected %d docs but found %v: %v", q, expected, result, err)
+			t.Fail()
+		}
+	}
+}
+
+func TestParseQuery_Errors(t *testing.T) {
+	for _, q := range []string{`"unterminated`, "color:red", "done:maybe", "created:someday", "priority:high", "OR golang", "golang OR", "tag:", "-"} {
+		if _, err := ParseQuery(q); err == nil {
+			t.Logf("%s: expected a parse error", q)
+			t.Fail()
+		}
+	}
+}
+
+func TestParseQuery_DueAndPriority(t *testing.T) {
+	indexer := NewIndexHandler(WithIndexHandlerInMemory())
+	defer indexer.Close()
+
+	today := time.Now()
+	day := func(days int) string { return today.AddDate(0, 0, days).Format(queryDateFormat) }
+	overdue := &ToDoDoc{BaseDoc: BaseDoc{ID: 1, Type: "todo"}, Task: "file taxes", Due: day(-3), Priority: 1}
+	dueToday := &ToDoDoc{BaseDoc: BaseDoc{ID: 2, Type: "todo"}, Task: "call mom", Due: day(0), Priority: 2}
+	later := &ToDoDoc{BaseDoc: BaseDoc{ID: 3, Type: "todo"}, Task: "plan trip", Due: day(30), Priority: 3}
+	someday := &ToDoDoc{BaseDoc: BaseDoc{ID: 4, Type: "todo"}, Task: "learn piano"}
+	indexer.IndexAll([]MiniDoc{overdue, dueToday, later, someday})
+
+	tests := map[string]int{
+		"due:<tomorrow":          2,
+		"due:<today":             1,
+		"due:today":              1,
+		"due:>=tomorrow":         1,
+		"priority:1":             1,
+		"priority:<=2":           2,
+		"priority:2..3":          2,
+		"priority:>0 due:<today": 1,
+	}
+	for q, expected := range tests {
+		result, err := indexer.Search(q, 0, 0)
+		if err != nil || len(result.Docs) != expected {
+			t.Logf("%s: expected %d docs but found %v: %v", q, expected, result, err)
+			t.Fail()
+		}
+	}
+
+	if !overdue.IsOverdue(today) || dueToday.IsOverdue(today) || someday.IsOverdue(today) {
+		t.Log("expected only the task due three days ago to be overdue")
+		t.Fail()
+	}
+}
+
+func TestToDoDoc_Validate(t *testing.T) {
+	todo := &ToDoDoc{Due: "tomorrow", Priority: 2, Recurrence: "Weekly"}
+	if err := todo.Validate(); err != nil || todo.Due != time.Now().AddDate(0, 0, 1).Format(queryDateFormat) || todo.Recurrence != "weekly" {
+		t.Logf("unexpected todo %+v after validating: %v", todo, err)
+		t.Fail()
+	}
+
+	todo.SetToggle(true)
+	if todo.Done || todo.Due != time.Now().AddDate(0, 0, 8).Format(queryDateFormat) {
+		t.Logf("expected a weekly todo to move a week on but got %+v", todo)
+		t.Fail()
+	}
+
+	failing := map[string]*ToDoDoc{
+		"bad due":            {Due: "next week"},
+		"bad priority":       {Priority: 4},
+		"bad recurrence":     {Due: "today", Recurrence: "hourly"},
+		"recurrence, no due": {Recurrence: "daily"},
+	}
+	for name, todo := range failing {
+		if err := todo.Validate(); err == nil {
+			t.Logf("%s: expected an error", name)
+			t.Fail()
+		}
+	}
+}
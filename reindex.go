@@ -0,0 +1,236 @@
+package minidoc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/blevesearch/bleve"
+	"os"
+	"strings"
+)
+
+// mappingHashKey is where the hash of the mapping an index was built with is kept in the index internal metadata
+var mappingHashKey = []byte("_mapping_hash")
+
+const rebuildBatchSize = 100
+
+// MappingHash fingerprints the current IndexMapping so an index built from an older one can be detected
+func MappingHash() (string, error) {
+	mapping, err := IndexMapping()
+	if err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(mapping)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func setMappingHash(index bleve.Index) error {
+	hash, err := MappingHash()
+	if err != nil {
+		return err
+	}
+	return index.SetInternal(mappingHashKey, []byte(hash))
+}
+
+// MappingChanged tells whether the index was built with a different mapping than the current one
+func (ih *IndexHandler) MappingChanged() bool {
+	hash, err := MappingHash()
+	if err != nil {
+		log.Errorf("hashing index mapping: %v", err)
+		return false
+	}
+
+	ih.mu.Lock()
+	stored, err := ih.index.GetInternal(mappingHashKey)
+	ih.mu.Unlock()
+	if err != nil {
+		log.Errorf("reading index mapping hash: %v", err)
+		return false
+	}
+
+	return string(stored) != hash
+}
+
+// Rebuild indexes every doc in db into a fresh index and swaps it in once complete, searches keep
+// using the old index meanwhile. progress is called after every batch with the number of docs done.
+func (ih *IndexHandler) Rebuild(db Store, progress func(done, total int)) error {
+	ih.mu.Lock()
+	ih.dirty = map[string]bool{}
+	ih.mu.Unlock()
+
+	index, path, err := ih.rebuild(db, progress)
+	if err != nil {
+		ih.mu.Lock()
+		ih.dirty = nil
+		ih.mu.Unlock()
+		if index != nil {
+			index.Close()
+		}
+		if len(path) > 0 {
+			os.RemoveAll(path)
+		}
+		return err
+	}
+
+	ih.mu.Lock()
+	defer ih.mu.Unlock()
+
+	// docs written while the rebuild was running
+	for docid := range ih.dirty {
+		if err := catchUp(index, db, docid); err != nil {
+			log.Errorf("catching up %s after rebuild: %v", docid, err)
+		}
+	}
+	ih.dirty = nil
+
+	return ih.swap(index, path)
+}
+
+func (ih *IndexHandler) rebuild(db Store, progress func(done, total int)) (bleve.Index, string, error) {
+	docs := []MiniDoc{}
+	for _, doctype := range doctypes {
+		found, err := db.ReadAll(doctype)
+		if err != nil {
+			return nil, "", err
+		}
+		docs = append(docs, found...)
+	}
+
+	mapping, err := IndexMapping()
+	if err != nil {
+		return nil, "", err
+	}
+
+	var index bleve.Index
+	path := ""
+	if ih.inMemory {
+		index, err = bleve.NewMemOnly(mapping)
+	} else {
+		path = ih.indexPath + ".rebuild"
+		os.RemoveAll(path)
+		index, err = bleve.New(path, mapping)
+	}
+	if err != nil {
+		log.Errorf("creating index for rebuild: %v", err)
+		return nil, path, err
+	}
+
+	for start := 0; start < len(docs); start += rebuildBatchSize {
+		end := start + rebuildBatchSize
+		if end > len(docs) {
+			end = len(docs)
+		}
+		batch := index.NewBatch()
+		for _, doc := range docs[start:end] {
+			if err := batch.Index(doc.GetIDString(), indexedDocument(doc)); err != nil {
+				return index, path, err
+			}
+		}
+		if err := index.Batch(batch); err != nil {
+			log.Errorf("indexing batch during rebuild: %v", err)
+			return index, path, err
+		}
+		progress(end, len(docs))
+	}
+
+	return index, path, setMappingHash(index)
+}
+
+func catchUp(index bleve.Index, db Store, docid string) error {
+	idparts := strings.Split(docid, ":")
+	if len(idparts) != 2 {
+		return fmt.Errorf("invalid doc id %s", docid)
+	}
+	doc, err := db.Read(toUnit32FromString(idparts[1]), idparts[0])
+	if err != nil {
+		// no longer in the store
+		return index.Delete(docid)
+	}
+	return index.Index(docid, indexedDocument(doc))
+}
+
+// swap replaces the current index with the rebuilt one, on disk the directories are renamed so
+// indexPath always holds a complete index. Must be called with ih.mu held.
+func (ih *IndexHandler) swap(index bleve.Index, path string) error {
+	if ih.inMemory {
+		ih.index.Close()
+		ih.index = index
+		return nil
+	}
+
+	if err := index.Close(); err != nil {
+		return err
+	}
+	if err := ih.index.Close(); err != nil {
+		return err
+	}
+
+	old := ih.indexPath + ".old"
+	os.RemoveAll(old)
+	if err := os.Rename(ih.indexPath, old); err != nil {
+		log.Errorf("moving old index aside: %v", err)
+		return ih.reopen()
+	}
+	if err := os.Rename(path, ih.indexPath); err != nil {
+		log.Errorf("moving rebuilt index in place: %v", err)
+		os.Rename(old, ih.indexPath)
+		return ih.reopen()
+	}
+
+	if err := ih.reopen(); err != nil {
+		return err
+	}
+	return os.RemoveAll(old)
+}
+
+func (ih *IndexHandler) reopen() error {
+	index, err := bleve.Open(ih.indexPath)
+	if err != nil {
+		log.Errorf("reopening index: %v", err)
+		return err
+	}
+	ih.index = index
+	return nil
+}
+
+// recoverInterruptedSwap puts the old index back when minidoc stopped between the two renames of swap
+func recoverInterruptedSwap(indexPath string) {
+	if _, err := os.Stat(indexPath); !os.IsNotExist(err) {
+		return
+	}
+	old := indexPath + ".old"
+	if _, err := os.Stat(old); err == nil {
+		log.Infof("restoring index from %s after an interrupted rebuild", old)
+		os.Rename(old, indexPath)
+	}
+}
+
+// RebuildIndexIfMappingChanged rebuilds the index in the background when its mapping is outdated,
+// progress is shown in the status bar
+func (app *SimpleApp) RebuildIndexIfMappingChanged() {
+	if !app.IndexHandler.MappingChanged() {
+		return
+	}
+	log.Info("index mapping changed, rebuilding index")
+
+	go func() {
+		err := app.IndexHandler.Rebuild(app.BucketHandler, func(done, total int) {
+			app.QueueUpdateDraw(func() {
+				app.SetStatus(fmt.Sprintf("[white:darkcyan] index mapping changed, rebuilding index %d/%d[white]", done, total))
+			})
+		})
+		app.QueueUpdateDraw(func() {
+			if err != nil {
+				log.Errorf("rebuilding index: %v", err)
+				app.SetStatus("[black:red]rebuilding index: " + err.Error() + "[white]")
+				return
+			}
+			app.SetStatus("[white:darkcyan] index rebuilt[white]")
+		})
+	}()
+}
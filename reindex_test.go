@@ -0,0 +1,52 @@
+package minidoc
+
+import (
+	"os"
+	"testing"
+)
+
+func TestIndexHandler_Rebuild(t *testing.T) {
+	indexPath := ".minidoc/rebuild_test_index"
+	os.RemoveAll(indexPath)
+	defer os.RemoveAll(indexPath)
+
+	indexer := NewIndexHandler(WithIndexHandlerIndexPath(indexPath))
+	defer indexer.Close()
+	if indexer.MappingChanged() {
+		t.Log("a new index should carry the current mapping hash")
+		t.Fail()
+	}
+
+	// pretend the index was built with an older mapping
+	indexer.index.SetInternal(mappingHashKey, []byte("outdated"))
+	if !indexer.MappingChanged() {
+		t.Log("mapping change should be detected")
+		t.Fail()
+	}
+
+	db := NewMemStore()
+	db.Write(GetTestNoteMiniDoc())
+
+	progressed := 0
+	err := indexer.Rebuild(db, func(done, total int) {
+		progressed = done
+	})
+	if err != nil || progressed != 1 {
+		t.Logf("rebuild progressed %d: %v", progressed, err)
+		t.Fail()
+	}
+
+	if indexer.MappingChanged() {
+		t.Log("rebuilt index should carry the current mapping hash")
+		t.Fail()
+	}
+	result, _ := indexer.Search("foo", 0, 0)
+	if len(result.Docs) != 1 {
+		t.Logf("expected rebuilt index to find 1 doc but found %d", len(result.Docs))
+		t.Fail()
+	}
+	if _, err := os.Stat(indexPath + ".old"); !os.IsNotExist(err) {
+		t.Log("old index should be removed after the swap")
+		t.Fail()
+	}
+}
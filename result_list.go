@@ -2,21 +2,54 @@ package minidoc
 
 import (
 	"fmt"
+	"github.com/7onetella/minidoc/config"
 	"github.com/gdamore/tcell"
 	"github.com/rivo/tview"
 	"reflect"
 	"strings"
+	"time"
 )
 
 type ResultList struct {
 	*tview.Table
 	Search *Search
+	// Columns are the names of the extra columns shown, see resultColumns
+	Columns []string
+}
+
+// resultColumns are the extra columns search_columns in config can add to the result list
+var resultColumns = map[string]func(doc MiniDoc) string{
+	"created": func(doc MiniDoc) string { return dateOnly(doc.GetCreatedDate()) },
+	"updated": func(doc MiniDoc) string { return dateOnly(doc.GetUpdatedDate()) },
+	"tags":    func(doc MiniDoc) string { return doc.GetTags() },
+}
+
+// dateOnly drops the time from dates stored as "2006-01-02 15:04:05" as well as RFC3339 dates from the index
+func dateOnly(date string) string {
+	if len(date) < len("2006-01-02") {
+		return date
+	}
+	return date[:len("2006-01-02")]
+}
+
+// configuredColumns are the known column names listed by search_columns in config
+func configuredColumns() []string {
+	columns := []string{}
+	for _, name := range config.Config().GetStringSlice("search_columns") {
+		if _, ok := resultColumns[name]; !ok {
+			log.Errorf("unknown search column %s", name)
+			continue
+		}
+		columns = append(columns, name)
+	}
+	return columns
 }
 
 func NewResultList(s *Search) *ResultList {
 	rl := &ResultList{
 		tview.NewTable(),
 		s,
+		configuredColumns(),
 	}
 
 	rl.SetBorders(false).
@@ -32,6 +65,16 @@ func NewResultList(s *Search) *ResultList {
 	return rl
 }
 
+// ColumnCount is the number of columns of a result row including the extra ones
+func (rl *ResultList) ColumnCount() int {
+	return fixedColumnCount + len(rl.Columns)
+}
+
+// FragmentsColumnIndex is the index of the last column, the one showing search fragments
+func (rl *ResultList) FragmentsColumnIndex() int {
+	return rl.ColumnCount() - 1
+}
+
 func (rl *ResultList) InsertColumns(size int) {
 	for i := 0; i < size; i++ {
 		rl.InsertColumn(0)
@@ -44,6 +87,13 @@ func (rl *ResultList) InputCapture() func(event *tcell.EventKey) *tcell.EventKey
 		//log.Debug("EventKey: " + event.Name())
 		eventKey := event.Key()
 
+		if s.IsHistoryMode() {
+			return rl.HistoryInputCapture(event)
+		}
+		if s.IsTrashMode() {
+			return rl.TrashInputCapture(event)
+		}
+
 		switch eventKey {
 		case tcell.KeyRune:
 			switch event.Rune() {
@@ -77,6 +127,15 @@ func (rl *ResultList) InputCapture() func(event *tcell.EventKey) *tcell.EventKey
 			case 't':
 				s.ToggleTogglable()
 				s.Preview(DIRECTION_NONE)
+			case 'n':
+				s.NextPage()
+				return nil
+			case 'p':
+				s.PrevPage()
+				return nil
+			case 's':
+				s.NextSortOrder()
+				return nil
 			default:
 				return s.DelegateEventHandlingMiniDoc(event)
 			}
@@ -114,6 +173,69 @@ func (rl *ResultList) InputCapture() func(event *tcell.EventKey) *tcell.EventKey
 	}
 }
 
+// HistoryInputCapture only allows navigating and restoring while revisions are listed
+func (rl *ResultList) HistoryInputCapture(event *tcell.EventKey) *tcell.EventKey {
+	s := rl.Search
+
+	switch event.Key() {
+	case tcell.KeyRune:
+		switch event.Rune() {
+		case 'j':
+			s.Preview(DOWN)
+		case 'k':
+			s.Preview(UP)
+		case 'r':
+			s.RestoreRevision(s.CurrentRowIndex)
+			return nil
+		}
+	case tcell.KeyEnter:
+		s.Preview(DIRECTION_NONE)
+		return nil
+	case tcell.KeyTab, tcell.KeyBacktab:
+		s.GoToSearchBar(false, "")
+		return nil
+	case tcell.KeyCtrlSpace:
+		s.GoToSearchBar(true, "")
+	default:
+		return nil
+	}
+
+	return event
+}
+
+// TrashInputCapture only allows navigating, restoring and purging while trashed docs are listed
+func (rl *ResultList) TrashInputCapture(event *tcell.EventKey) *tcell.EventKey {
+	s := rl.Search
+
+	switch event.Key() {
+	case tcell.KeyRune:
+		switch event.Rune() {
+		case 'j':
+			s.Preview(DOWN)
+		case 'k':
+			s.Preview(UP)
+		case 'r':
+			s.RestoreTrashed(s.CurrentRowIndex)
+			return nil
+		case 'p':
+			s.PurgeTrashedConfirmation(s.CurrentRowIndex)
+			return nil
+		}
+	case tcell.KeyEnter:
+		s.Preview(DIRECTION_NONE)
+		return nil
+	case tcell.KeyTab, tcell.KeyBacktab:
+		s.GoToSearchBar(false, "")
+		return nil
+	case tcell.KeyCtrlSpace:
+		s.GoToSearchBar(true, "")
+	default:
+		return nil
+	}
+
+	return event
+}
+
 func NewCellWithBG(reference interface{}, text string, color, bg tcell.Color) *tview.TableCell {
 	return &tview.TableCell{
 		Reference:       reference,
@@ -182,15 +304,22 @@ func (rl *ResultList) GetCellRefBool(rowIndex, colIndex int) (bool, error) {
 }
 
 func (rl *ResultList) LoadMiniDocFromDB(rowIndex int) (MiniDoc, error) {
+	if rl.Search.IsHistoryMode() && rowIndex < len(rl.Search.Revisions) {
+		return rl.Search.Revisions[rowIndex].Doc, nil
+	}
+	if rl.Search.IsTrashMode() && rowIndex < len(rl.Search.TrashItems) {
+		return rl.Search.TrashItems[rowIndex].Doc, nil
+	}
+
 	id, _ := rl.GetCellRefUint32(rowIndex, idColumnIndex)
 
 	doctype, _ := rl.GetCellRefString(rowIndex, typeColumnIndex)
 
-	fragments, _ := rl.GetCellRefString(rowIndex, fragmentsColumnIndex)
+	fragments, _ := rl.GetCellRefString(rowIndex, rl.FragmentsColumnIndex())
 
 	isSelected, _ := rl.GetCellRefBool(rowIndex, selectedColumnIndex)
 
-	doc, err := rl.Search.App.BucketHandler.Read(id, doctype)
+	doc, err := rl.Search.App.DataHandler.Store.Read(id, doctype)
 	if err != nil {
 		log.Debugf("read error: %v", err)
 		return nil, err
@@ -210,7 +339,7 @@ func (rl *ResultList) UpdateRow(row int, doc MiniDoc) {
 
 	if doc.IsTogglable() {
 		// swap it out with the one from db
-		docFromDB, _ := rl.Search.App.DataHandler.BucketHandler.Read(doc.GetID(), doc.GetType())
+		docFromDB, _ := rl.Search.App.DataHandler.Store.Read(doc.GetID(), doc.GetType())
 		doc = docFromDB
 		doc.SetSearchFragments(fragments)
 		doc.SetIsSelected(selected)
@@ -219,11 +348,39 @@ func (rl *ResultList) UpdateRow(row int, doc MiniDoc) {
 	cd := []CellData{
 		CellData{doctype, doc.GetIDString()},
 		CellData{doc.IsSelected(), doc.IsSelectedString()},
-		CellData{doc.GetToggle(), doc.GetToggleValueAsString()},
-		CellData{fragments + cellpadding, fragments + cellpadding},
+		CellData{IsToggled(doc), toggleMark(doc)},
 		CellData{doc.GetID(), ""},
 	}
+	cd = append(cd, rl.ColumnCells(doc)...)
+	cd = append(cd, CellData{fragments + cellpadding, fragments + cellpadding})
 	rl.SetColumnCells(row, cd)
+
+	if due, ok := doc.(dueDoc); ok && due.IsOverdue(time.Now()) {
+		for i := range cd {
+			rl.GetCell(row, i).SetTextColor(tcell.ColorRed)
+		}
+	}
+}
+
+// ColumnCells are the cells of the columns picked with search_columns, they go between the fixed cells and the fragments
+func (rl *ResultList) ColumnCells(doc MiniDoc) []CellData {
+	cd := []CellData{}
+	for _, name := range rl.Columns {
+		value := resultColumns[name](doc)
+		cd = append(cd, CellData{value, value})
+	}
+	return cd
+}
+
+// dueDoc is a doc that can be overdue, its row is shown in red
+type dueDoc interface {
+	IsOverdue(now time.Time) bool
+}
+
+// SetRowSelected marks row as selected without reading the doc from db
+func (rl *ResultList) SetRowSelected(row int, selected bool) {
+	doc := &BaseDoc{Selected: selected}
+	rl.SetCell(row, selectedColumnIndex, NewCell(selected, doc.IsSelectedString(), tcell.ColorWhite))
 }
 
 func (rl *ResultList) MoveRow(direction int) {
@@ -249,4 +406,8 @@ func (rl *ResultList) MoveRow(direction int) {
 		rl.RemoveRow(prevRow)
 		rl.Search.SelectRow(row)
 	}
+
+	if rl.Search.IsCollectionMode() {
+		rl.Search.SaveCollectionOrder()
+	}
 }
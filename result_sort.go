@@ -0,0 +1,63 @@
+package minidoc
+
+import (
+	"github.com/7onetella/minidoc/config"
+)
+
+// SortOption names a sort order of search results
+type SortOption struct {
+	Name  string
+	Order []string
+}
+
+// sortOptions are cycled through in this order by the sort key of the result list
+var sortOptions = []SortOption{
+	{"score", []string{"-_score"}},
+	{"created", []string{"-created_date", "-_score"}},
+	{"updated", []string{"-updated_date", "-_score"}},
+	{"title", []string{titleSortField, "-_score"}},
+	{"type", []string{"type", "-_score"}},
+}
+
+// sortOptionIndex finds the sort option called name, unknown names fall back to score
+func sortOptionIndex(name string) int {
+	for i, option := range sortOptions {
+		if option.Name == name {
+			return i
+		}
+	}
+	return 0
+}
+
+// ConfiguredSortOrder is the sort order named by search_sort in config
+func ConfiguredSortOrder() []string {
+	return sortOptions[sortOptionIndex(config.Config().GetString("search_sort"))].Order
+}
+
+// saveSortOption sets search_sort to name and writes the config file back, without a config file the sort option
+// lasts until minidoc exits
+func saveSortOption(cfg config.Provider, name string) error {
+	cfg.Set("search_sort", name)
+	if len(cfg.ConfigFileUsed()) == 0 {
+		return nil
+	}
+	return cfg.WriteConfig()
+}
+
+// NextSortOrder sorts search results by the sort option after the current one and lists the current query again,
+// the new order is saved to the config file for the next start
+func (s *Search) NextSortOrder() {
+	cfg := config.Config()
+	option := sortOptions[(sortOptionIndex(cfg.GetString("search_sort"))+1)%len(sortOptions)]
+	if err := saveSortOption(cfg, option.Name); err != nil {
+		log.Errorf("saving sort order %s: %v", option.Name, err)
+	}
+	s.App.DataHandler.Indexer.SetSortOrder(option.Order...)
+
+	status := "sorted by " + option.Name
+	if len(s.Query) > 0 && s.CurrentPage != nil {
+		s.GoToPage(0)
+		status += ", " + s.CurrentPage.Stat()
+	}
+	s.App.SetStatus("[white:darkcyan] "+status+"[white]", s.PageIndicator())
+}
@@ -0,0 +1,133 @@
+package minidoc
+
+import (
+	"fmt"
+	"github.com/rivo/tview"
+)
+
+const savedSearchBucketName = "_saved_searches"
+
+type SavedSearch struct {
+	Name  string
+	Query string
+}
+
+// SaveSearch stores query under name, an existing saved search with the same name is replaced
+func (tx *BucketTx) SaveSearch(name, query string) error {
+	return tx.kv.Put(savedSearchBucketName, []byte(name), []byte(query))
+}
+
+// DeleteSavedSearch removes the saved search called name
+func (tx *BucketTx) DeleteSavedSearch(name string) error {
+	if tx.kv.Get(savedSearchBucketName, []byte(name)) == nil {
+		return fmt.Errorf("no saved search named %s", name)
+	}
+	return tx.kv.Delete(savedSearchBucketName, []byte(name))
+}
+
+// SavedSearches returns every saved search ordered by name
+func (tx *BucketTx) SavedSearches() ([]SavedSearch, error) {
+	searches := []SavedSearch{}
+	err := tx.kv.ForEach(savedSearchBucketName, func(k, v []byte) error {
+		searches = append(searches, SavedSearch{string(k), string(v)})
+		return nil
+	})
+	return searches, err
+}
+
+// SavedSearchPage is a search page pinned to a saved query, the query runs again every time the page is shown
+type SavedSearchPage struct {
+	*Search
+	Name  string
+	Query string
+}
+
+func NewSavedSearchPage(saved SavedSearch) *SavedSearchPage {
+	return &SavedSearchPage{
+		Search: NewSearch(),
+		Name:   saved.Name,
+		Query:  saved.Query,
+	}
+}
+
+func (p *SavedSearchPage) Page() (title string, content tview.Primitive) {
+	_, content = p.Search.Page()
+	return p.Name, content
+}
+
+func (p *SavedSearchPage) GetInstance() interface{} {
+	return p
+}
+
+// Refresh runs the saved query again
+func (p *SavedSearchPage) Refresh() {
+	p.SearchFor(p.Query)
+}
+
+// MenuLabel shows the number of docs matching the saved query next to its name
+func (p *SavedSearchPage) MenuLabel() string {
+	result, err := p.App.DataHandler.Indexer.Search(p.Query, 0, 1)
+	if err != nil {
+		return p.Name + "(?)"
+	}
+	return fmt.Sprintf("%s(%d)", p.Name, result.Total)
+}
+
+// SavedSearchPages loads a page for every saved search
+func (app *SimpleApp) SavedSearchPages() []PageItem {
+	var searches []SavedSearch
+	err := app.DataHandler.Store.View(func(tx *BucketTx) error {
+		var err error
+		searches, err = tx.SavedSearches()
+		return err
+	})
+	if err != nil {
+		log.Errorf("reading saved searches: %v", err)
+		return nil
+	}
+
+	pages := []PageItem{}
+	for _, saved := range searches {
+		pages = append(pages, NewSavedSearchPage(saved))
+	}
+	return pages
+}
+
+// SaveSearch pins the last query run on this page as a page of its own
+func (s *Search) SaveSearch(name string) {
+	if len(s.Query) == 0 {
+		s.App.SetStatus("[black:red]run a search before saving it[white]")
+		return
+	}
+	pagesHandler := s.App.PagesHandler
+	if pagesHandler.HasPage(name) {
+		s.App.SetStatus("[black:red]a page named " + name + " already exists[white]")
+		return
+	}
+
+	saved := SavedSearch{name, s.Query}
+	err := s.App.DataHandler.Store.Update(func(tx *BucketTx) error {
+		return tx.SaveSearch(saved.Name, saved.Query)
+	})
+	if err != nil {
+		log.Errorf("saving search %s: %v", name, err)
+		s.App.SetStatus("[black:red]saving search: " + err.Error() + "[white]")
+		return
+	}
+
+	pagesHandler.AddPage(s.App, NewSavedSearchPage(saved))
+	pagesHandler.GotoPageByTitle(name)
+}
+
+// UnsaveSearch deletes a saved search and removes its page
+func (s *Search) UnsaveSearch(name string) {
+	err := s.App.DataHandler.Store.Update(func(tx *BucketTx) error {
+		return tx.DeleteSavedSearch(name)
+	})
+	if err != nil {
+		s.App.SetStatus("[black:red]removing saved search: " + err.Error() + "[white]")
+		return
+	}
+	s.App.PagesHandler.RemovePage(name)
+	s.App.SetStatus("[white:darkcyan] saved search " + name + " removed[white]")
+}
@@ -0,0 +1,75 @@
+package minidoc
+
+import (
+	"github.com/rivo/tview"
+	"strings"
+	"testing"
+)
+
+func TestBucketTx_SavedSearches(t *testing.T) {
+	db := NewMemStore()
+
+	db.Update(func(tx *BucketTx) error {
+		tx.SaveSearch("work", "tag:work done:false")
+		return tx.SaveSearch("later", "watch_later:true")
+	})
+
+	var searches []SavedSearch
+	db.View(func(tx *BucketTx) error {
+		searches, _ = tx.SavedSearches()
+		return nil
+	})
+	if len(searches) != 2 || searches[0] != (SavedSearch{"later", "watch_later:true"}) {
+		t.Logf("unexpected saved searches %v", searches)
+		t.Fail()
+	}
+
+	err := db.Update(func(tx *BucketTx) error {
+		return tx.DeleteSavedSearch("work")
+	})
+	if err != nil {
+		t.Logf("deleting saved search: %v", err)
+		t.Fail()
+	}
+	err = db.Update(func(tx *BucketTx) error {
+		return tx.DeleteSavedSearch("work")
+	})
+	if err == nil {
+		t.Log("deleting an unknown saved search should fail")
+		t.Fail()
+	}
+}
+
+type testPageItem struct {
+	SetAppAdapter
+	title string
+}
+
+func (t *testPageItem) Page() (string, tview.Primitive) {
+	return t.title, tview.NewBox()
+}
+
+func TestPagesHandler_RemovePage(t *testing.T) {
+	p := &PagesHandler{
+		Pages:     tview.NewPages(),
+		PageIndex: map[string]string{},
+		MenuBar:   tview.NewTextView().SetRegions(true),
+		PageItems: []PageItem{&testPageItem{title: "Search"}, &testPageItem{title: "work"}, &testPageItem{title: "later"}},
+	}
+	p.LoadPages(nil)
+	p.GotoPageByTitle("later")
+
+	p.RemovePage("work")
+	if p.HasPage("work") || p.PageIndex["later"] != "1" || p.CurrPageIndex != 1 || len(p.PageItems) != 2 {
+		t.Logf("unexpected pages %v at %d", p.PageIndex, p.CurrPageIndex)
+		t.Fail()
+	}
+	if name, _ := p.Pages.GetFrontPage(); name != "1" || p.Pages.HasPage("2") {
+		t.Logf("expected later shown as page 1 but got page %s", name)
+		t.Fail()
+	}
+	if menu := p.MenuBar.GetText(true); strings.Contains(menu, "work") || !strings.Contains(menu, "later") {
+		t.Logf("unexpected menu %q", menu)
+		t.Fail()
+	}
+}
@@ -1,8 +1,10 @@
 package minidoc
 
 import (
+	"encoding/json"
 	"fmt"
 	"github.com/0xAX/notificator"
+	"github.com/7onetella/minidoc/config"
 	"github.com/atotto/clipboard"
 	"strconv"
 	"strings"
@@ -28,6 +30,20 @@ type Search struct {
 	RegionCount     int
 	RegionDocIDs    map[int]string
 	Referenced      *tview.TextView
+	HistoryDoc      MiniDoc
+	Revisions       []Revision
+	TrashItems      []TrashItem
+	Query           string
+	CurrentPage     *SearchResult
+	AllSelected     bool
+	// Collection is the name of the collection listed, see ShowCollection
+	Collection string
+	// Related are the docs most like the previewed one, see ShowRelated
+	Related      []MiniDoc
+	RelatedIndex int
+	// MatchedTerms are the terms the search matched in the previewed doc, they are highlighted in the preview
+	MatchedTerms map[string]bool
+	typeAhead    typeAhead
 }
 
 func NewSearch() *Search {
@@ -83,7 +99,7 @@ func (s *Search) GetInstance() interface{} {
 	return s
 }
 
-var words = []string{"@new", "@generate", "@tag", "@untag", "@export", "@import"}
+var words = []string{"@new", "@generate", "@tag", "@untag", "@tag-rename", "@tag-merge", "@export", "@import", "@history", "@trash", "@save", "@unsave", "@collection", "@collection-save", "@collection-delete"}
 
 func (s *Search) InitSearchBar(placeholder string) {
 	//log.Debug("resetting search bar")
@@ -98,6 +114,8 @@ func (s *Search) InitSearchBar(placeholder string) {
 		input.SetInputCapture(s.InputCapture(input))
 	}
 
+	input.SetChangedFunc(s.SearchAsYouType)
+
 	input.SetAutocompleteFunc(func(currentText string) (entries []string) {
 		if len(currentText) == 0 {
 			return
@@ -129,9 +147,10 @@ func (s *Search) InputCapture(input *tview.InputField) func(event *tcell.EventKe
 			}
 
 			// if term0 starts with @ and terms length is 1 then disregard enter
-			if len(terms) == 1 && strings.HasPrefix(terms[0], "@") {
+			if len(terms) == 1 && strings.HasPrefix(terms[0], "@") && !contains(noArgCommands, terms[0][1:]) {
 				return event
 			}
+			s.CancelSearchAsYouType()
 			done := s.Search(text)
 			if done {
 				return nil
@@ -201,6 +220,12 @@ func (s *Search) PreviewInputCapture() func(event *tcell.EventKey) *tcell.EventK
 				return nil
 			case 'i':
 				s.Edit()
+			case 'm':
+				s.ShowRelated()
+				return nil
+			case 'g':
+				s.GoToRelated()
+				return nil
 			default:
 				if s.RegionCount > 0 {
 					//regionText := s.Detail.GetRegionText(fmt.Sprintf("%d", s.RegionID))
@@ -210,7 +235,7 @@ func (s *Search) PreviewInputCapture() func(event *tcell.EventKey) *tcell.EventK
 					doctype := sslice[0]
 					id := sslice[1]
 
-					doc, _ := s.App.DataHandler.BucketHandler.Read(toUnit32FromString(id), doctype)
+					doc, _ := s.App.DataHandler.Store.Read(toUnit32FromString(id), doctype)
 					if doc != nil {
 						doc.HandleEvent(event)
 						//s.App.StatusBar.SetText(doc.GetTitle())
@@ -244,7 +269,7 @@ func (s *Search) ShowNextReferenced() {
 	doctype := sslice[0]
 	id := sslice[1]
 
-	doc, _ := s.App.DataHandler.BucketHandler.Read(toUnit32FromString(id), doctype)
+	doc, _ := s.App.DataHandler.Store.Read(toUnit32FromString(id), doctype)
 	if doc != nil {
 		json := JsonMapFrom(doc)
 		jh := NewJsonMapWrapper(json)
@@ -272,14 +297,17 @@ func (s *Search) ShowNextReferenced() {
 }
 
 func (s *Search) HideReferenced() {
+	s.Related = nil
 	s.Rows.RemoveItem(s.Referenced)
 }
 
 const typeColumnIndex = 0
 const selectedColumnIndex = 1
 const toggledColumnIndex = 2
-const fragmentsColumnIndex = 3
-const idColumnIndex = 4
+const idColumnIndex = 3
+
+// the columns chosen by search_columns come after the id, the fragments column is always last
+const fixedColumnCount = 5
 
 func (s *Search) Search(searchby string) bool {
 	searchTerms := ""
@@ -293,26 +321,20 @@ func (s *Search) Search(searchby string) bool {
 		return true
 	}
 
-	for _, doctype := range doctypes {
-		// e.g. url:10
-		if strings.HasPrefix(searchTerms, doctype+":") {
-			terms := strings.Split(searchTerms, " ")
-			if len(terms) == 1 {
-				idstr := searchTerms[strings.Index(searchTerms, ":")+1:]
-				v, _ := strconv.Atoi(idstr)
-				id := uint32(v)
-				doc, err := s.App.BucketHandler.Read(id, doctype)
-				// most likely record not found
-				if err != nil {
-					s.UpdateResult([]MiniDoc{})
-					return false
-				}
-				doc.SetSearchFragments(doc.GetTitle())
-				s.UpdateResult([]MiniDoc{doc})
-				return false
-			}
+	// e.g. url:10
+	if doctype, id, ok := parseDocID(searchTerms); ok {
+		doc, err := s.App.DataHandler.Store.Read(id, doctype)
+		// most likely record not found
+		if err != nil {
+			s.UpdateResult([]MiniDoc{})
+			return false
 		}
+		doc.SetSearchFragments(doc.GetTitle())
+		s.UpdateResult([]MiniDoc{doc})
+		return false
+	}
 
+	for _, doctype := range doctypes {
 		// e.g. url is typed
 		if searchTerms == doctype {
 			s.HandleCommand("@list " + searchTerms)
@@ -320,21 +342,135 @@ func (s *Search) Search(searchby string) bool {
 		}
 	}
 
-	result, stat := s.App.IndexHandler.Search(searchTerms)
-	if result == nil {
-		s.debug("result is empty")
+	result, err := s.App.DataHandler.Indexer.Search(searchTerms, 0, pageSize())
+	if err != nil {
+		s.App.SetStatus("[black:red]query: " + err.Error() + "[white]")
 		return false
 	}
-	s.App.SetStatus("[white:darkcyan] " + stat + "[white]")
-
-	s.UpdateResult(result)
+	s.ShowPage(searchTerms, result)
 	return false
 }
 
+// parseDocID reads a doc id lookup such as url:10, note:kubernetes is a query on the note field instead
+func parseDocID(text string) (string, uint32, bool) {
+	i := strings.Index(text, ":")
+	if i < 0 || !contains(doctypes, text[:i]) {
+		return "", 0, false
+	}
+	id, err := strconv.ParseUint(text[i+1:], 10, 32)
+	if err != nil {
+		return "", 0, false
+	}
+	return text[:i], uint32(id), true
+}
+
+// SearchFor puts query in the search bar and lists what it finds
+func (s *Search) SearchFor(query string) {
+	if input, ok := s.SearchBar.GetFormItem(0).(*tview.InputField); ok {
+		input.SetText(query)
+	}
+	s.CancelSearchAsYouType()
+	if done := s.Search(query); done {
+		return
+	}
+	s.ResultList.ScrollToBeginning()
+	s.SelectRow(0)
+	s.GoToSearchResult()
+}
+
+func pageSize() int {
+	size := config.Config().GetInt("search_page_size")
+	if size <= 0 {
+		return 100
+	}
+	return size
+}
+
+// ShowPage lists a page of hits for query and keeps track of it for paging
+func (s *Search) ShowPage(query string, result *SearchResult) {
+	allSelected := s.AllSelected && s.Query == query
+
+	s.UpdateResult(result.Docs)
+	s.Query = query
+	s.CurrentPage = result
+	s.ResultList.SetTitle(resultTitle(result.Tags))
+	s.AllSelected = allSelected
+	if allSelected {
+		for i := 0; i < s.ResultList.GetRowCount(); i++ {
+			s.ResultList.SetRowSelected(i, true)
+		}
+	}
+	s.App.SetStatus("[white:darkcyan] "+result.Stat()+"[white]", s.PageIndicator())
+}
+
+// resultTitle shows the most used tags among all hits
+func resultTitle(tags []TagCount) string {
+	title := "Results"
+	for i, tag := range tags {
+		if i == 5 {
+			break
+		}
+		title += fmt.Sprintf(" %s(%d)", tag.Tag, tag.Count)
+	}
+	return title
+}
+
+// PageIndicator tells which page of the current query is shown, e.g. "page 2/5 "
+func (s *Search) PageIndicator() string {
+	if s.CurrentPage == nil || s.CurrentPage.Total == 0 {
+		return ""
+	}
+	size := pageSize()
+	return fmt.Sprintf("page %d/%d ", s.CurrentPage.Offset/size+1, (s.CurrentPage.Total+size-1)/size)
+}
+
+// NextPage shows the hits after the current page
+func (s *Search) NextPage() {
+	if s.CurrentPage == nil || !s.CurrentPage.HasMore() {
+		s.App.SetStatus("[white:darkcyan] no more pages[white]", s.PageIndicator())
+		return
+	}
+	s.GoToPage(s.CurrentPage.Offset + len(s.CurrentPage.Docs))
+}
+
+// PrevPage shows the hits before the current page
+func (s *Search) PrevPage() {
+	if s.CurrentPage == nil || s.CurrentPage.Offset == 0 {
+		s.App.SetStatus("[white:darkcyan] already on the first page[white]", s.PageIndicator())
+		return
+	}
+	offset := s.CurrentPage.Offset - pageSize()
+	if offset < 0 {
+		offset = 0
+	}
+	s.GoToPage(offset)
+}
+
+func (s *Search) GoToPage(offset int) {
+	result, err := s.App.DataHandler.Indexer.Search(s.Query, offset, pageSize())
+	if err != nil {
+		s.App.SetStatus("[black:red]query: " + err.Error() + "[white]")
+		return
+	}
+	s.ShowPage(s.Query, result)
+	s.ResultList.ScrollToBeginning()
+	s.SelectRow(0)
+	s.GoToSearchResult()
+}
+
 func (s *Search) UpdateResult(result []MiniDoc) {
+	s.HistoryDoc = nil
+	s.Revisions = nil
+	s.TrashItems = nil
+	s.Collection = ""
+	s.MatchedTerms = nil
+	s.Query = ""
+	s.CurrentPage = nil
+	s.AllSelected = false
+	s.ResultList.SetTitle("Results")
 	s.ResultList.Clear()
 	// doc type
-	s.ResultList.InsertColumns(5)
+	s.ResultList.InsertColumns(s.ResultList.ColumnCount())
 
 	// Display search result
 	for _, doc := range result {
@@ -358,6 +494,12 @@ func EditWithVim(app *SimpleApp, doc MiniDoc) (MiniDoc, bool) {
 	for _, fieldName := range doc.GetViEditFields() {
 		UUID := uuid.New().String()
 		file := fmt.Sprintf("/tmp/%s", UUID)
+		// vim picks the filetype of code by its extension
+		if code, ok := doc.(codeDoc); ok && fieldName == code.CodeField() {
+			if ext := languageExtension(code.CodeLanguage()); len(ext) > 0 {
+				file += "." + ext
+			}
+		}
 		// write field value to the file
 		WriteToFile(file, jh.string(fieldName))
 		// let user edit
@@ -442,6 +584,11 @@ func (s *Search) GoToPreview() {
 	s.App.Draw()
 }
 
+// searchAware docs are handed the search before they handle an event, e.g. to suspend the app while a command runs
+type searchAware interface {
+	SetSearch(s *Search)
+}
+
 func (s *Search) DelegateEventHandlingMiniDoc(event *tcell.EventKey) *tcell.EventKey {
 
 	// in search result
@@ -455,11 +602,17 @@ func (s *Search) DelegateEventHandlingMiniDoc(event *tcell.EventKey) *tcell.Even
 		return nil
 	}
 
+	if aware, ok := doc.(searchAware); ok {
+		aware.SetSearch(s)
+	}
+
 	// so far open browser for url
 	doc.HandleEvent(event)
 
-	// write any change from event handling
-	s.App.DataHandler.Write(doc)
+	// write any change from event handling, copying a snippet leaves the doc as it is
+	if err := s.WriteChanged(doc); err != nil {
+		log.Errorf("writing %s: %v", doc.GetIDString(), err)
+	}
 
 	// update the view
 	s.Preview(DIRECTION_NONE)
@@ -467,6 +620,21 @@ func (s *Search) DelegateEventHandlingMiniDoc(event *tcell.EventKey) *tcell.Even
 	return nil
 }
 
+// WriteChanged writes doc unless it is the same as the stored doc, so a change is written once however many
+// times it is handed in
+func (s *Search) WriteChanged(doc MiniDoc) error {
+	stored, err := s.App.DataHandler.Store.Read(doc.GetID(), doc.GetType())
+	if err == nil {
+		before, _ := json.Marshal(stored.GetJSON())
+		after, _ := json.Marshal(doc.GetJSON())
+		if string(before) == string(after) {
+			return nil
+		}
+	}
+	_, err = s.App.DataHandler.Write(doc)
+	return err
+}
+
 func (s *Search) Preview(direction int) {
 	if s.IsEditMode {
 		s.Columns.RemoveItem(s.EditForm)
@@ -475,6 +643,12 @@ func (s *Search) Preview(direction int) {
 	}
 
 	s.UpdateCurrRowIndexFromSelectedRow(direction)
+	s.Related = nil
+
+	if s.IsHistoryMode() {
+		s.PreviewRevision(s.CurrentRowIndex)
+		return
+	}
 
 	log.Debugf("current row %d", s.CurrentRowIndex)
 	doc, err := s.LoadMiniDocFromDB(s.CurrentRowIndex)
@@ -485,7 +659,13 @@ func (s *Search) Preview(direction int) {
 
 	// move keys like j and k controls the selection
 	// result list select only makes sense for shifting the focus over and selecting
-	s.App.SetStatus(fmt.Sprintf("[white:darkcyan] spacebar <- select | %s", doc.GetAvailableActions()), fmt.Sprintf("row %d ", s.CurrentRowIndex))
+	actions := fmt.Sprintf("spacebar <- select | %s", doc.GetAvailableActions())
+	if s.IsTrashMode() {
+		actions = "r <- restore from trash | p <- purge permanently"
+	}
+	s.App.SetStatus(fmt.Sprintf("[white:darkcyan] %s", actions), fmt.Sprintf("row %d ", s.CurrentRowIndex))
+
+	s.MatchedTerms = s.matchedTerms(doc)
 
 	json := JsonMapFrom(doc)
 	jh := NewJsonMapWrapper(json)
@@ -505,6 +685,15 @@ func (s *Search) Preview(direction int) {
 		content += "\n"
 		content += fmt.Sprintf("[white]%s:[white] ", fieldNameCleaned)
 
+		if code, ok := doc.(codeDoc); ok && fieldName == code.CodeField() {
+			for _, line := range highlightCode(v, code.CodeLanguage()) {
+				content += "\n"
+				content += line
+			}
+			content += "[darkcyan]\n"
+			continue
+		}
+
 		lines := strings.Split(v, "\n")
 		if len(lines) > 1 {
 			for _, line := range lines {
@@ -525,6 +714,23 @@ func (s *Search) Preview(direction int) {
 	fmt.Fprintf(s.Detail, content)
 }
 
+// matchedTerms are the terms the current search matched in doc
+func (s *Search) matchedTerms(doc MiniDoc) map[string]bool {
+	terms := map[string]bool{}
+	if s.CurrentPage == nil {
+		return terms
+	}
+	for _, hit := range s.CurrentPage.Docs {
+		if hit.GetIDString() == doc.GetIDString() {
+			for _, term := range hit.GetMatchedTerms() {
+				terms[term] = true
+			}
+			break
+		}
+	}
+	return terms
+}
+
 func Transpose(line string, s *Search) string {
 	tokens := strings.Split(line, " ")
 	n := len(tokens)
@@ -536,14 +742,14 @@ func Transpose(line string, s *Search) string {
 			sslice := strings.Split(docid, ":")
 			doctype := sslice[0]
 			id := sslice[1]
-			doc, _ := s.App.DataHandler.BucketHandler.Read(toUnit32FromString(id), doctype)
+			doc, _ := s.App.DataHandler.Store.Read(toUnit32FromString(id), doctype)
 			if doc != nil {
 				content[i] = fmt.Sprintf(`["%d"][yellow]%s[darkcyan][""]`, s.RegionCount, doc.GetTitle())
 				s.RegionDocIDs[s.RegionCount] = docid
 			}
 			s.RegionCount++
 		} else {
-			content[i] = fmt.Sprintf("%s", token)
+			content[i] = highlightMatched(token, s.MatchedTerms)
 		}
 	}
 	out := ""
@@ -596,31 +802,75 @@ func ConfirmationModal(app *SimpleApp, message string, action func()) {
 }
 
 func (s *Search) BatchDeleteActionFunc() {
+	docs, err := s.SelectedDocs()
+	if err != nil {
+		return
+	}
+
+	log.Debugf("deleting %d docs", len(docs))
+	err = s.App.DataHandler.DeleteAll(docs)
+	if err != nil {
+		log.Errorf("deleting %d docs failed: %v", len(docs), err)
+		return
+	}
+}
+
+// SelectedDocs loads every selected row from db, every hit of the query when all rows are selected
+func (s *Search) SelectedDocs() ([]MiniDoc, error) {
+	if s.AllSelected && s.CurrentPage != nil {
+		return s.AllQueryDocs()
+	}
+
+	docs := []MiniDoc{}
 	for i := 0; i < s.ResultList.GetRowCount(); i++ {
-		log.Debugf("current row %d", s.CurrentRowIndex)
 		doc, err := s.LoadMiniDocFromDB(i)
 		if err != nil {
 			log.Errorf("minidoc from failed: %v", err)
-			return
+			return nil, err
 		}
 
 		if !doc.IsSelected() {
 			log.Debugf("row %d not selected skipping", i)
 			continue
 		}
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
 
-		log.Debugf("deleting %v", doc)
-		s.App.DataHandler.Delete(doc)
+// AllQueryDocs loads every hit of the current query from db, not just the page shown
+func (s *Search) AllQueryDocs() ([]MiniDoc, error) {
+	result, err := s.App.DataHandler.Indexer.Search(s.Query, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	docs := []MiniDoc{}
+	for _, hit := range result.Docs {
+		doc, err := s.App.DataHandler.Store.Read(hit.GetID(), hit.GetType())
 		if err != nil {
-			log.Errorf("deleting %v failed: %v", doc, err)
-			return
+			log.Errorf("minidoc from failed: %v", err)
+			return nil, err
 		}
-		//log.Debugf("removing row %v", i)
-		//s.ResultList.RemoveRow(i)
+		doc.SetIsSelected(true)
+		docs = append(docs, doc)
 	}
+	return docs, nil
 }
 
 func (s *Search) SelectAllRows() {
+	if s.CurrentPage != nil {
+		s.AllSelected = !s.AllSelected
+		for i := 0; i < s.ResultList.GetRowCount(); i++ {
+			s.ResultList.SetRowSelected(i, s.AllSelected)
+		}
+		if s.AllSelected {
+			s.App.SetStatus(fmt.Sprintf("[white:darkcyan] all %d matches selected[white]", s.CurrentPage.Total), s.PageIndicator())
+		}
+		s.App.ForceDraw()
+		return
+	}
+
 	for i := 0; i < s.ResultList.GetRowCount(); i++ {
 		log.Debugf("current row %d", s.CurrentRowIndex)
 		doc, err := s.LoadMiniDocFromDB(i)
@@ -648,10 +898,10 @@ func (s *Search) ToggleAllRows() {
 			return
 		}
 
-		if !doc.GetToggle() {
-			doc.SetToggle(true)
-		} else {
-			doc.SetToggle(false)
+		doc, err = ToggleDoc(doc, !IsToggled(doc))
+		if err != nil {
+			log.Errorf("toggling row %d: %v", i, err)
+			continue
 		}
 		_, err = s.App.DataHandler.Write(doc)
 		if err == nil {
@@ -686,6 +936,8 @@ func (s *Search) ToggleSelected() {
 
 	if doc.IsSelected() {
 		doc.SetIsSelected(false)
+		// a deselected row means the rest of the query result isn't selected either
+		s.AllSelected = false
 	} else {
 		doc.SetIsSelected(true)
 	}
@@ -701,10 +953,10 @@ func (s *Search) ToggleTogglable() {
 		return
 	}
 
-	if doc.GetToggle() {
-		doc.SetToggle(false)
-	} else {
-		doc.SetToggle(true)
+	doc, err = ToggleDoc(doc, !IsToggled(doc))
+	if err != nil {
+		log.Errorf("toggling: %v", err)
+		return
 	}
 
 	s.App.DataHandler.Write(doc)
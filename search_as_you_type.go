@@ -0,0 +1,113 @@
+package minidoc
+
+import (
+	"context"
+	"github.com/7onetella/minidoc/config"
+	"strings"
+	"sync"
+	"time"
+)
+
+// typeAhead runs the query in the search bar while it is being typed, only the latest query's result is shown
+type typeAhead struct {
+	mu         sync.Mutex
+	timer      *time.Timer
+	cancel     context.CancelFunc
+	generation uint64
+}
+
+func typeAheadDelay() time.Duration {
+	return time.Duration(config.Config().GetInt("search_as_you_type_delay_ms")) * time.Millisecond
+}
+
+// SearchAsYouType schedules text to be searched once typing pauses, queries still running for earlier text are cancelled
+func (s *Search) SearchAsYouType(text string) {
+	delay := typeAheadDelay()
+	if delay <= 0 {
+		return
+	}
+
+	generation := s.typeAhead.stop()
+	if !isIncrementalQuery(text) {
+		return
+	}
+
+	ta := &s.typeAhead
+	ta.mu.Lock()
+	defer ta.mu.Unlock()
+	ta.timer = time.AfterFunc(delay, func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		if !ta.start(generation, cancel) {
+			cancel()
+			return
+		}
+		defer cancel()
+
+		result, err := s.App.DataHandler.Indexer.SearchContext(ctx, text, 0, pageSize())
+		if err != nil || !ta.current(generation) {
+			return
+		}
+
+		s.App.QueueUpdateDraw(func() {
+			if !ta.current(generation) {
+				return
+			}
+			s.ShowPage(text, result)
+			// keep typing
+			s.App.SetFocus(s.SearchBar)
+		})
+	})
+}
+
+// CancelSearchAsYouType drops pending and running incremental queries, e.g. when Enter runs the query right away
+func (s *Search) CancelSearchAsYouType() {
+	s.typeAhead.stop()
+}
+
+// stop cancels whatever is scheduled or running and returns the generation for the next query
+func (ta *typeAhead) stop() uint64 {
+	ta.mu.Lock()
+	defer ta.mu.Unlock()
+
+	if ta.timer != nil {
+		ta.timer.Stop()
+		ta.timer = nil
+	}
+	if ta.cancel != nil {
+		ta.cancel()
+		ta.cancel = nil
+	}
+	ta.generation++
+	return ta.generation
+}
+
+func (ta *typeAhead) start(generation uint64, cancel context.CancelFunc) bool {
+	ta.mu.Lock()
+	defer ta.mu.Unlock()
+
+	if generation != ta.generation {
+		return false
+	}
+	ta.cancel = cancel
+	return true
+}
+
+func (ta *typeAhead) current(generation uint64) bool {
+	ta.mu.Lock()
+	defer ta.mu.Unlock()
+
+	return generation == ta.generation
+}
+
+// isIncrementalQuery leaves commands, doc id lookups and half typed queries to Enter
+func isIncrementalQuery(text string) bool {
+	text = strings.TrimSpace(text)
+	if len(text) == 0 || strings.HasPrefix(text, "@") {
+		return false
+	}
+	if _, _, ok := parseDocID(text); ok || contains(doctypes, text) {
+		return false
+	}
+	_, err := ParseQuery(text)
+	return err == nil
+}
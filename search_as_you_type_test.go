@@ -0,0 +1,52 @@
+package minidoc
+
+import (
+	"context"
+	"testing"
+)
+
+func TestIsIncrementalQuery(t *testing.T) {
+	tests := map[string]bool{
+		"golang":       true,
+		"tag:golang":   true,
+		"":             false,
+		"@tag work":    false,
+		"url:10":       false,
+		"note:k8s":     true,
+		"note":         false,
+		`"unfinished`:  false,
+		"tag:":         false,
+		"done:false -": false,
+	}
+	for text, expected := range tests {
+		if isIncrementalQuery(text) != expected {
+			t.Logf("%q: expected incremental %v", text, expected)
+			t.Fail()
+		}
+	}
+}
+
+func TestTypeAhead_Stale(t *testing.T) {
+	ta := &typeAhead{}
+
+	first := ta.stop()
+	ctx, cancel := context.WithCancel(context.Background())
+	if !ta.start(first, cancel) {
+		t.Log("latest query should start")
+		t.Fail()
+	}
+
+	second := ta.stop()
+	if ctx.Err() == nil {
+		t.Log("running query should be cancelled by the next keystroke")
+		t.Fail()
+	}
+	if ta.current(first) || !ta.current(second) {
+		t.Log("only the latest query should be current")
+		t.Fail()
+	}
+	if ta.start(first, func() {}) {
+		t.Log("stale query should not start")
+		t.Fail()
+	}
+}
@@ -0,0 +1,144 @@
+package minidoc
+
+import (
+	"fmt"
+	"github.com/7onetella/minidoc/config"
+	"github.com/blevesearch/bleve"
+	"github.com/blevesearch/bleve/search"
+	"github.com/blevesearch/bleve/search/query"
+	"github.com/rivo/tview"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// maxSimilarTerms caps the words of a doc that go into its more like this query
+const maxSimilarTerms = 25
+
+// similarTagBoost makes a shared tag count more than a shared word
+const similarTagBoost = 3.0
+
+// similarQuery matches docs sharing words of the indexed fields or tags with doc, doc itself is left out
+func similarQuery(doc MiniDoc) query.Query {
+	jh := NewJsonMapWrapper(JsonMapFrom(doc))
+
+	seen := map[string]bool{}
+	words := []string{}
+	for _, field := range indexSpecOf(doc.GetType()).Text {
+		for _, word := range strings.Fields(jh.string(field)) {
+			word = strings.ToLower(strings.TrimFunc(word, func(r rune) bool {
+				return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+			}))
+			if len([]rune(word)) < 3 || seen[word] {
+				continue
+			}
+			seen[word] = true
+			words = append(words, word)
+		}
+	}
+	// longer words tend to say more about the topic than short ones
+	sort.SliceStable(words, func(i, j int) bool {
+		return len([]rune(words[i])) > len([]rune(words[j]))
+	})
+	if len(words) > maxSimilarTerms {
+		words = words[:maxSimilarTerms]
+	}
+
+	queries := []query.Query{}
+	for _, word := range words {
+		queries = append(queries, bleve.NewMatchQuery(word))
+	}
+	for _, tag := range strings.Fields(doc.GetTags()) {
+		q := bleve.NewTermQuery(strings.ToLower(tag))
+		q.SetField("tags")
+		q.SetBoost(similarTagBoost)
+		queries = append(queries, q)
+	}
+	if len(queries) == 0 {
+		return nil
+	}
+
+	bq := bleve.NewBooleanQuery()
+	bq.AddShould(queries...)
+	bq.SetMinShould(1)
+	bq.AddMustNot(bleve.NewDocIDQuery([]string{doc.GetIDString()}))
+	return bq
+}
+
+// Similar returns up to limit docs most like doc, best match first
+func (ih *IndexHandler) Similar(doc MiniDoc, limit int) ([]MiniDoc, error) {
+	q := similarQuery(doc)
+	if q == nil || limit <= 0 {
+		return []MiniDoc{}, nil
+	}
+
+	request := bleve.NewSearchRequestOptions(q, limit, 0, false)
+	request.Sort = search.SortOrder{&search.SortScore{Desc: true}}
+	request.Fields = baseSearchFields
+
+	ih.mu.Lock()
+	sr, err := ih.index.Search(request)
+	ih.mu.Unlock()
+	if err != nil {
+		log.Errorf("similar search error: %v", err)
+		return nil, err
+	}
+	return hitDocs(sr.Hits, nil), nil
+}
+
+// ShowRelated lists the docs most like the previewed one in the referenced pane, pressing it again highlights the next one
+func (s *Search) ShowRelated() {
+	if s.Related == nil {
+		count := config.Config().GetInt("related_docs_count")
+		if count <= 0 {
+			return
+		}
+		doc, err := s.LoadMiniDocFromDB(s.CurrentRowIndex)
+		if err != nil {
+			log.Errorf("minidoc from failed: %v", err)
+			return
+		}
+		hits, err := s.App.DataHandler.Indexer.Similar(doc, count)
+		if err != nil {
+			s.App.SetStatus("[black:red]finding related docs: " + err.Error() + "[white]")
+			return
+		}
+		related := []MiniDoc{}
+		for _, hit := range hits {
+			doc, err := s.App.DataHandler.Store.Read(hit.GetID(), hit.GetType())
+			if err != nil {
+				log.Errorf("minidoc from failed: %v", err)
+				continue
+			}
+			related = append(related, doc)
+		}
+		if len(related) == 0 {
+			s.App.SetStatus("[white:darkcyan] no related docs[white]")
+			return
+		}
+		s.Related = related
+		s.RelatedIndex = 0
+	} else {
+		s.RelatedIndex = (s.RelatedIndex + 1) % len(s.Related)
+	}
+
+	s.Referenced.Clear()
+	s.Referenced.SetTitle("Related")
+	for i, doc := range s.Related {
+		fmt.Fprintf(s.Referenced, "[\"r%d\"][white]%s [darkcyan]%s[white][\"\"]\n", i, doc.GetIDString(), tview.Escape(doc.GetTitle()))
+	}
+	s.Referenced.Highlight(fmt.Sprintf("r%d", s.RelatedIndex))
+	s.Rows.RemoveItem(s.Referenced)
+	s.Rows.AddItem(s.Referenced, 0, 3, false)
+	s.App.SetStatus("[white:darkcyan] m <- next related doc | g <- go to related doc[white]")
+}
+
+// GoToRelated lists the highlighted related doc in the result list
+func (s *Search) GoToRelated() {
+	if len(s.Related) == 0 {
+		return
+	}
+	docid := s.Related[s.RelatedIndex].GetIDString()
+	s.HideReferenced()
+	s.SearchFor(docid)
+}
@@ -0,0 +1,28 @@
+package minidoc
+
+import (
+	"testing"
+)
+
+func TestIndexHandler_Similar(t *testing.T) {
+	indexer := NewIndexHandler(WithIndexHandlerInMemory())
+	defer indexer.Close()
+
+	doc := &NoteDoc{BaseDoc: BaseDoc{ID: 1, Type: "note", Title: "kubernetes deployments", Tags: "k8s"}, Note: "rolling updates of kubernetes pods"}
+	alike := &NoteDoc{BaseDoc: BaseDoc{ID: 2, Type: "note", Title: "kubernetes pods", Tags: "k8s"}, Note: "pods restart on updates"}
+	tagged := &URLDoc{BaseDoc: BaseDoc{ID: 3, Type: "url", Title: "helm charts", Tags: "k8s"}}
+	other := &NoteDoc{BaseDoc: BaseDoc{ID: 4, Type: "note", Title: "sourdough bread"}, Note: "feed the starter"}
+	indexer.IndexAll([]MiniDoc{doc, alike, tagged, other})
+
+	related, err := indexer.Similar(doc, 5)
+	if err != nil || len(related) != 2 || related[0].GetIDString() != alike.GetIDString() || related[1].GetIDString() != tagged.GetIDString() {
+		t.Logf("expected note:2 then url:3 but got %v: %v", related, err)
+		t.Fail()
+	}
+
+	related, _ = indexer.Similar(doc, 1)
+	if len(related) != 1 {
+		t.Logf("expected the limit to hold but got %v", related)
+		t.Fail()
+	}
+}
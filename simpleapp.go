@@ -11,6 +11,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 func init() {
@@ -121,32 +122,76 @@ func NewSimpleApp(opts ...SimpleAppOption) *SimpleApp {
 		opt(app)
 	}
 
+	// doctypes declared in the config file are needed by the migration and the index mapping
+	if err := RegisterConfigDocTypes(config.Config()); err != nil {
+		log.Errorf("registering doctypes from config: %v", err)
+	}
+
 	app.BucketHandler = NewBucketHandler(
 		WithBucketHandlerDebug(app.DebugView.Debug),
 		WithBucketHandlerDBPath(app.dataFolderPath+"/store.db"),
 	)
+
+	// upgrade stored records before the index is opened
+	migrated, err := MigrateSchema(app.BucketHandler)
+	if err != nil {
+		log.Fatalf("migrating schema: %v", err)
+	}
+	if migrated > 0 {
+		log.Infof("migrated %d docs to schema version %d", migrated, CurrentSchemaVersion())
+		app.docsReindexed = true
+	}
+
 	app.IndexHandler = NewIndexHandler(
 		WithIndexHandlerDebug(app.DebugView.Debug),
 		WithIndexHandlerIndexPath(app.dataFolderPath+"/index"),
+		WithIndexHandlerFuzzyFallback(config.Config().GetInt("search_fuzzy_min_hits")),
+		WithIndexHandlerSortOrder(ConfiguredSortOrder()...),
 	)
 	app.DataHandler = &DataHandler{
 		app.BucketHandler,
 		app.IndexHandler,
 	}
 
+	recovered, err := app.DataHandler.RecoverPending()
+	if err != nil {
+		log.Errorf("recovering pending index updates: %v", err)
+	}
+	if recovered > 0 {
+		log.Infof("recovered %d pending index updates", recovered)
+	}
+
+	purgeDays := config.Config().GetInt("trash_purge_days")
+	if purgeDays > 0 {
+		purged, err := app.DataHandler.PurgeTrash(time.Duration(purgeDays) * 24 * time.Hour)
+		if err != nil {
+			log.Errorf("purging trash: %v", err)
+		}
+		if purged > 0 {
+			log.Infof("purged %d docs from trash", purged)
+		}
+	}
+
 	if app.docsReindexed {
 		Reindex(app.IndexHandler, app.BucketHandler)
 	}
+	app.RebuildIndexIfMappingChanged()
 
 	app.MenuBar.Highlight(strconv.Itoa(0))
 
+	pageHandler.PageItems = append(pageHandler.PageItems, app.SavedSearchPages()...)
 	pageHandler.LoadPages(app)
+	pageHandler.RefreshMenu()
 
 	layout.AddItem(app.Rows, 0, 1, true)
 	app.Layout = layout
 
 	app.SetInputCapture(app.GetInputCaptureFunc())
 
+	if config.Config().GetBool("notify_overdue_todos") {
+		app.NotifyOverdueTodos()
+	}
+
 	app.Draw()
 	app.SetStatus("[white:darkcyan] Ctrl-h <- navigate left | Ctrl-l <- navigate right[white]" +
 		"                                                                                                     ")
@@ -154,11 +199,47 @@ func NewSimpleApp(opts ...SimpleAppOption) *SimpleApp {
 	return app
 }
 
-func Reindex(ih *IndexHandler, db *BucketHandler) {
+// overdueQuery finds the open todos due before today
+const overdueQuery = "type:todo done:false due:<today"
+
+// maxNotifiedTodos caps the overdue todos named in the notification
+const maxNotifiedTodos = 10
+
+// NotifyOverdueTodos lists the open todos past their due day in a desktop notification
+func (app *SimpleApp) NotifyOverdueTodos() {
+	result, err := app.IndexHandler.Search(overdueQuery, 0, maxNotifiedTodos)
+	if err != nil {
+		log.Errorf("searching overdue todos: %v", err)
+		return
+	}
+	if len(result.Docs) == 0 {
+		return
+	}
+
+	tasks := []string{}
+	for _, hit := range result.Docs {
+		doc, err := app.DataHandler.Store.Read(hit.GetID(), hit.GetType())
+		if err != nil || doc == nil {
+			continue
+		}
+		tasks = append(tasks, doc.GetTitle())
+	}
+	if result.Total > len(result.Docs) {
+		tasks = append(tasks, fmt.Sprintf("and %d more", result.Total-len(result.Docs)))
+	}
+
+	s, ok := app.PagesHandler.GetPageItem("Search").GetInstance().(*Search)
+	if !ok {
+		return
+	}
+	s.Notify(fmt.Sprintf("%d overdue todos", result.Total), strings.Join(tasks, "\n"))
+}
+
+func Reindex(ih Indexer, db Store) {
 	buckets := doctypes
 	for _, bucket := range buckets {
 		docs, _ := db.ReadAll(bucket)
-		ih.debug(bucket + ":retrieved:" + strconv.Itoa(len(docs)))
+		log.Debug(bucket + ":retrieved:" + strconv.Itoa(len(docs)))
 		for _, doc := range docs {
 			err := ih.Index(doc)
 			if err != nil {
@@ -169,6 +250,14 @@ func Reindex(ih *IndexHandler, db *BucketHandler) {
 	}
 }
 
+// appKeys are handled by GetInputCaptureFunc, the result list and the forms, no doctype can start a new doc with them.
+// Tab is Ctrl-I and Enter is Ctrl-M to the terminal, Ctrl-Space is the zero key which stands for no new doc key
+var appKeys = []tcell.Key{
+	tcell.KeyCtrlC, tcell.KeyCtrlD, tcell.KeyCtrlE, tcell.KeyCtrlH, tcell.KeyCtrlL, tcell.KeyCtrlO,
+	tcell.KeyCtrlA, tcell.KeyCtrlJ, tcell.KeyCtrlK,
+	tcell.KeyTab, tcell.KeyBacktab, tcell.KeyEnter,
+}
+
 func (app *SimpleApp) GetInputCaptureFunc() func(event *tcell.EventKey) *tcell.EventKey {
 	return func(event *tcell.EventKey) *tcell.EventKey {
 		pagesHandler := app.PagesHandler
@@ -186,21 +275,14 @@ func (app *SimpleApp) GetInputCaptureFunc() func(event *tcell.EventKey) *tcell.E
 			app.ToggleDebug()
 		case tcell.KeyCtrlD:
 			app.GoToDebugView()
-		case tcell.KeyCtrlN:
-			NewDocFlow("note", app)
-			defer app.Draw()
-			return nil
-		case tcell.KeyCtrlU:
-			NewDocFlow("url", app)
-			defer app.Draw()
-			return nil
-		case tcell.KeyCtrlT:
-			NewDocFlow("todo", app)
-			defer app.Draw()
-			return nil
 		case tcell.KeyCtrlC:
 			app.Exit()
 		default:
+			if dt, found := docTypeForKey(event.Key()); found {
+				NewDocFlow(dt.Name, app)
+				defer app.Draw()
+				return nil
+			}
 			//if app.delegatedKeyEvent != nil {
 			//	delegated := app.delegatedKeyEvent(event)
 			//	return delegated
@@ -218,6 +300,7 @@ func (app *SimpleApp) Exit() {
 		SetDoneFunc(func(buttonIndex int, buttonLabel string) {
 			if buttonLabel == "Quit" {
 				app.Stop()
+				app.DataHandler.Close()
 				os.Exit(0)
 			} else {
 				if err := app.SetRoot(app.Layout, true).Run(); err != nil {
@@ -232,6 +315,7 @@ func (app *SimpleApp) Exit() {
 		}
 	}
 	app.Stop()
+	app.DataHandler.Close()
 	os.Exit(0)
 }
 
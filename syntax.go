@@ -0,0 +1,241 @@
+package minidoc
+
+import (
+	"github.com/rivo/tview"
+	"strings"
+	"unicode"
+)
+
+// colors of the syntax highlighting in the preview
+const (
+	syntaxPlainColor   = "[darkcyan]"
+	syntaxKeywordColor = "[orange]"
+	syntaxStringColor  = "[green]"
+	syntaxCommentColor = "[gray]"
+	syntaxNumberColor  = "[lightblue]"
+)
+
+// syntax is just enough of a language to color its keywords, strings, comments and numbers
+type syntax struct {
+	keywords     []string
+	lineComments []string
+	// blockComment opens and closes a comment that may span lines, e.g. /* and */
+	blockComment [2]string
+	quotes       string
+	extension    string
+}
+
+var cStyleComments = [2]string{"/*", "*/"}
+
+var syntaxes = map[string]syntax{
+	"go": {
+		keywords: []string{"break", "case", "chan", "const", "continue", "default", "defer", "else", "fallthrough",
+			"for", "func", "go", "goto", "if", "import", "interface", "map", "package", "range", "return", "select",
+			"struct", "switch", "type", "var", "nil", "true", "false"},
+		lineComments: []string{"//"},
+		blockComment: cStyleComments,
+		quotes:       "\"'`",
+		extension:    "go",
+	},
+	"python": {
+		keywords: []string{"and", "as", "assert", "async", "await", "break", "class", "continue", "def", "del",
+			"elif", "else", "except", "finally", "for", "from", "global", "if", "import", "in", "is", "lambda",
+			"nonlocal", "not", "or", "pass", "raise", "return", "try", "while", "with", "yield", "None", "True",
+			"False"},
+		lineComments: []string{"#"},
+		quotes:       "\"'",
+		extension:    "py",
+	},
+	"shell": {
+		keywords: []string{"if", "then", "else", "elif", "fi", "for", "while", "until", "do", "done", "case",
+			"esac", "in", "function", "return", "export", "local", "echo", "exit"},
+		lineComments: []string{"#"},
+		quotes:       "\"'",
+		extension:    "sh",
+	},
+	"javascript": {
+		keywords: []string{"async", "await", "break", "case", "catch", "class", "const", "continue", "default",
+			"delete", "do", "else", "export", "extends", "finally", "for", "function", "if", "import", "in",
+			"instanceof", "let", "new", "return", "switch", "this", "throw", "try", "typeof", "var", "while",
+			"yield", "null", "undefined", "true", "false"},
+		lineComments: []string{"//"},
+		blockComment: cStyleComments,
+		quotes:       "\"'`",
+		extension:    "js",
+	},
+	"java": {
+		keywords: []string{"abstract", "break", "case", "catch", "class", "continue", "default", "do", "else",
+			"extends", "final", "finally", "for", "if", "implements", "import", "instanceof", "interface", "new",
+			"package", "private", "protected", "public", "return", "static", "super", "switch", "this", "throw",
+			"throws", "try", "void", "while", "null", "true", "false"},
+		lineComments: []string{"//"},
+		blockComment: cStyleComments,
+		quotes:       "\"'",
+		extension:    "java",
+	},
+	"sql": {
+		keywords: []string{"select", "from", "where", "and", "or", "not", "insert", "into", "values", "update",
+			"set", "delete", "create", "table", "drop", "alter", "join", "left", "right", "inner", "outer", "on",
+			"group", "by", "order", "having", "limit", "as", "distinct", "null", "is", "in", "like"},
+		lineComments: []string{"--"},
+		blockComment: cStyleComments,
+		quotes:       "'\"",
+		extension:    "sql",
+	},
+	"yaml": {
+		keywords:     []string{"true", "false", "null", "yes", "no"},
+		lineComments: []string{"#"},
+		quotes:       "\"'",
+		extension:    "yaml",
+	},
+}
+
+// syntaxAliases are other names a snippet language goes by
+var syntaxAliases = map[string]string{
+	"golang": "go",
+	"py":     "python",
+	"sh":     "shell",
+	"bash":   "shell",
+	"zsh":    "shell",
+	"js":     "javascript",
+	"yml":    "yaml",
+}
+
+// syntaxOf looks up the syntax of language, found is false for languages that are not highlighted
+func syntaxOf(language string) (syntax, bool) {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if name, ok := syntaxAliases[language]; ok {
+		language = name
+	}
+	s, found := syntaxes[language]
+	return s, found
+}
+
+// languageExtension is the file extension vim picks the filetype of language by
+func languageExtension(language string) string {
+	if s, found := syntaxOf(language); found {
+		return s.extension
+	}
+	// whatever else the language is called, it must not take the temp file out of its folder
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, language)
+}
+
+// highlightCode colors code in language with tview color tags, one entry per line, the code itself is escaped
+// so brackets show as they are
+func highlightCode(code, language string) []string {
+	lines := strings.Split(code, "\n")
+	s, found := syntaxOf(language)
+	if !found {
+		for i, line := range lines {
+			lines[i] = syntaxPlainColor + tview.Escape(line)
+		}
+		return lines
+	}
+
+	keywords := map[string]bool{}
+	for _, keyword := range s.keywords {
+		keywords[keyword] = true
+	}
+	// sql keywords are written in either case
+	caseless := s.extension == "sql"
+	commentStarts := append([]string{s.blockComment[0]}, s.lineComments...)
+
+	inBlockComment := false
+	for i, line := range lines {
+		out := ""
+		runes := []rune(line)
+		for j := 0; j < len(runes); {
+			rest := string(runes[j:])
+
+			if inBlockComment {
+				end := strings.Index(rest, s.blockComment[1])
+				if end < 0 {
+					out += syntaxCommentColor + tview.Escape(rest)
+					j = len(runes)
+					continue
+				}
+				comment := rest[:end+len(s.blockComment[1])]
+				out += syntaxCommentColor + tview.Escape(comment)
+				j += len([]rune(comment))
+				inBlockComment = false
+				continue
+			}
+
+			if len(s.blockComment[0]) > 0 && strings.HasPrefix(rest, s.blockComment[0]) {
+				inBlockComment = true
+				out += syntaxCommentColor + tview.Escape(s.blockComment[0])
+				j += len([]rune(s.blockComment[0]))
+				continue
+			}
+
+			if startsWithAny(rest, s.lineComments) {
+				out += syntaxCommentColor + tview.Escape(rest)
+				break
+			}
+
+			r := runes[j]
+			switch {
+			case strings.ContainsRune(s.quotes, r):
+				end := j + 1
+				for end < len(runes) && runes[end] != r {
+					if runes[end] == '\\' {
+						end++
+					}
+					end++
+				}
+				if end >= len(runes) {
+					end = len(runes) - 1
+				}
+				out += syntaxStringColor + tview.Escape(string(runes[j:end+1]))
+				j = end + 1
+			case unicode.IsLetter(r) || r == '_':
+				end := j
+				for end < len(runes) && (unicode.IsLetter(runes[end]) || unicode.IsDigit(runes[end]) || runes[end] == '_') {
+					end++
+				}
+				word := string(runes[j:end])
+				if keywords[word] || (caseless && keywords[strings.ToLower(word)]) {
+					out += syntaxKeywordColor + word
+				} else {
+					out += syntaxPlainColor + word
+				}
+				j = end
+			case unicode.IsDigit(r):
+				end := j
+				for end < len(runes) && (unicode.IsDigit(runes[end]) || runes[end] == '.' || runes[end] == 'x') {
+					end++
+				}
+				out += syntaxNumberColor + string(runes[j:end])
+				j = end
+			default:
+				end := j
+				for end < len(runes) && !unicode.IsLetter(runes[end]) && !unicode.IsDigit(runes[end]) &&
+					runes[end] != '_' && !strings.ContainsRune(s.quotes, runes[end]) &&
+					!startsWithAny(string(runes[end:]), commentStarts) {
+					end++
+				}
+				if end == j {
+					end++
+				}
+				out += syntaxPlainColor + tview.Escape(string(runes[j:end]))
+				j = end
+			}
+		}
+		lines[i] = out
+	}
+	return lines
+}
+
+func startsWithAny(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if len(prefix) > 0 && strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package minidoc
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHighlightCode(t *testing.T) {
+	code := "func main() { // start\n\tx := []string{\"a\", 'b'}\n\treturn 42\n}"
+	lines := highlightCode(code, "golang")
+	if len(lines) != 4 {
+		t.Logf("expected 4 lines but got %d", len(lines))
+		t.FailNow()
+	}
+
+	expected := map[int][]string{
+		0: {"[orange]func", "[darkcyan]main", "[gray]// start"},
+		1: {"[darkcyan] := []", "[green]\"a\"", "[green]'b'"},
+		2: {"[orange]return", "[lightblue]42"},
+	}
+	for i, parts := range expected {
+		for _, part := range parts {
+			if !strings.Contains(lines[i], part) {
+				t.Logf("expected %q in line %d %q", part, i, lines[i])
+				t.Fail()
+			}
+		}
+	}
+
+	lines = highlightCode("SELECT id /* all\nof them */ FROM docs", "sql")
+	if !strings.HasPrefix(lines[0], "[orange]SELECT") || !strings.HasPrefix(lines[1], "[gray]of them */") ||
+		!strings.Contains(lines[1], "[orange]FROM") {
+		t.Logf("unexpected sql highlighting %q", lines)
+		t.Fail()
+	}
+
+	lines = highlightCode("see [note:1]", "cobol")
+	if lines[0] != "[darkcyan]see [note:1[]" {
+		t.Logf("expected an unknown language escaped only but got %q", lines[0])
+		t.Fail()
+	}
+}
+
+func TestLanguageExtension(t *testing.T) {
+	tests := map[string]string{
+		"go":        "go",
+		"Python":    "py",
+		"bash":      "sh",
+		"rust":      "rust",
+		"../../etc": "etc",
+		"":          "",
+	}
+	for language, expected := range tests {
+		if ext := languageExtension(language); ext != expected {
+			t.Logf("%s: expected %q but got %q", language, expected, ext)
+			t.Fail()
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package minidoc
+
+import (
+	"fmt"
+	"github.com/gdamore/tcell"
+	"github.com/rivo/tview"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// tagSeparator splits hierarchical tags, e.g. lang/go is a child of lang
+const tagSeparator = "/"
+
+// renameTag replaces from in tag along with everything under it, renaming lang to language turns lang/go into language/go.
+// A rename of from into its own child is left alone as it would nest again on every run
+func renameTag(tag, from, to string) (string, bool) {
+	if isUnderTag(to, from) {
+		return tag, false
+	}
+	if strings.EqualFold(tag, from) {
+		return to, true
+	}
+	if isUnderTag(tag, from) {
+		return to + tag[len(from):], true
+	}
+	return tag, false
+}
+
+// isUnderTag tells whether tag sits under parent, lang/go sits under lang
+func isUnderTag(tag, parent string) bool {
+	return len(tag) > len(parent) && strings.EqualFold(tag[:len(parent)+1], parent+tagSeparator)
+}
+
+// checkRenames refuses a rename of a tag into its own child, lang into lang/x
+func checkRenames(renames map[string]string) error {
+	for from, to := range renames {
+		if isUnderTag(to, from) {
+			return fmt.Errorf("cannot rename %s into its own child %s", from, to)
+		}
+	}
+	return nil
+}
+
+// RenameTags applies renames to the space separated tags, duplicates left by merged tags are dropped. When
+// several renames match a tag the longest one wins, so lang/go is renamed before lang
+func RenameTags(tags string, renames map[string]string) string {
+	froms := []string{}
+	for from := range renames {
+		froms = append(froms, from)
+	}
+	sort.Slice(froms, func(i, j int) bool {
+		if len(froms[i]) != len(froms[j]) {
+			return len(froms[i]) > len(froms[j])
+		}
+		return froms[i] < froms[j]
+	})
+
+	renamed := []string{}
+	for _, tag := range strings.Fields(tags) {
+		for _, from := range froms {
+			if t, ok := renameTag(tag, from, renames[from]); ok {
+				tag = t
+				break
+			}
+		}
+		if !contains(renamed, tag) {
+			renamed = append(renamed, tag)
+		}
+	}
+	return strings.Join(renamed, " ")
+}
+
+// RenameTags rewrites the tags of every doc of every doctype in a single transaction and returns how many docs changed
+func (dh *DataHandler) RenameTags(renames map[string]string) (int, error) {
+	if err := checkRenames(renames); err != nil {
+		return 0, err
+	}
+
+	changed := []MiniDoc{}
+	err := dh.Store.Update(func(tx *BucketTx) error {
+		for _, doctype := range doctypes {
+			docs, err := tx.ReadAll(doctype)
+			if err != nil {
+				return err
+			}
+			for _, doc := range docs {
+				tags := RenameTags(doc.GetTags(), renames)
+				if tags == strings.Join(strings.Fields(doc.GetTags()), " ") {
+					continue
+				}
+				doc.SetTags(tags)
+				if _, err := tx.Write(doc); err != nil {
+					return err
+				}
+				if err := tx.MarkPending(doc, pendingOpIndex); err != nil {
+					return err
+				}
+				changed = append(changed, doc)
+			}
+		}
+		return nil
+	})
+	if err != nil || len(changed) == 0 {
+		return 0, err
+	}
+
+	err = dh.Indexer.IndexAll(changed)
+	if err != nil {
+		return len(changed), err
+	}
+	return len(changed), dh.clearPending(changed)
+}
+
+// TagsPage lists every tag with the number of docs carrying it
+type TagsPage struct {
+	Title string
+	App   *SimpleApp
+	List  *tview.Table
+	Tags  []TagCount
+}
+
+func NewTagsPage() *TagsPage {
+	return &TagsPage{
+		Title: "Tags",
+		List:  tview.NewTable(),
+	}
+}
+
+func (t *TagsPage) SetApp(app *SimpleApp) {
+	t.App = app
+}
+
+func (t *TagsPage) GetInstance() interface{} {
+	return t
+}
+
+func (t *TagsPage) Page() (title string, content tview.Primitive) {
+	t.List.SetBorders(false).
+		SetSeparator(' ').
+		SetSelectable(true, false).
+		SetSelectedStyle(tcell.ColorGray, tcell.ColorWhite, tcell.AttrNone).
+		SetTitle(t.Title)
+	t.List.SetBorder(true)
+	t.List.SetBorderPadding(1, 1, 2, 2)
+	t.List.SetInputCapture(t.InputCapture())
+	t.List.SetSelectedFunc(func(row, column int) {
+		t.ShowTagged(row)
+	})
+
+	return t.Title, tview.NewFlex().AddItem(t.List, 0, 1, true)
+}
+
+// Refresh reloads tag counts from the index, the page is refreshed every time it is switched to
+func (t *TagsPage) Refresh() {
+	tags, err := t.App.DataHandler.Indexer.Tags()
+	if err != nil {
+		t.App.SetStatus("[black:red]reading tags: " + err.Error() + "[white]")
+		return
+	}
+	t.Tags = tags
+
+	t.List.Clear()
+	for i, tag := range tags {
+		t.List.SetCell(i, 0, NewCell(tag.Tag, tag.Tag, tcell.ColorWhite))
+		t.List.SetCell(i, 1, NewCell(tag.Count, strconv.Itoa(tag.Count), tcell.ColorDarkCyan))
+	}
+	t.List.ScrollToBeginning()
+	t.List.Select(0, 0)
+
+	t.App.SetStatus(fmt.Sprintf("[white:darkcyan] %d tags | Enter <- list tagged docs | r <- refresh[white]", len(tags)))
+}
+
+func (t *TagsPage) InputCapture() func(event *tcell.EventKey) *tcell.EventKey {
+	return func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case 'r':
+				t.Refresh()
+				return nil
+			}
+		}
+		return event
+	}
+}
+
+// ShowTagged switches to the search page listing docs tagged with the tag at row
+func (t *TagsPage) ShowTagged(row int) {
+	if row >= len(t.Tags) {
+		return
+	}
+
+	s, ok := t.App.PagesHandler.GetPageItem("Search").GetInstance().(*Search)
+	if !ok {
+		return
+	}
+	t.App.PagesHandler.GotoPageByTitle("Search")
+	s.SearchFor("tag:" + t.Tags[row].Tag)
+}
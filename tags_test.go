@@ -0,0 +1,61 @@
+package minidoc
+
+import (
+	"testing"
+)
+
+func TestRenameTags(t *testing.T) {
+	tests := []struct {
+		tags     string
+		renames  map[string]string
+		expected string
+	}{
+		{"lang/go vim", map[string]string{"lang": "language"}, "language/go vim"},
+		{"golang go", map[string]string{"golang": "lang/go", "go": "lang/go"}, "lang/go"},
+		{"language", map[string]string{"lang": "x"}, "language"},
+		{"lang lang/go lang/go/mod", map[string]string{"lang": "x", "lang/go": "y"}, "x y y/mod"},
+		{"lang/go/mod lang/rust", map[string]string{"lang/go": "y", "lang": "x"}, "y/mod x/rust"},
+		{"lang lang/go", map[string]string{"lang": "lang/x"}, "lang lang/go"},
+	}
+	for _, test := range tests {
+		if renamed := RenameTags(test.tags, test.renames); renamed != test.expected {
+			t.Logf("%s renamed with %v: expected %s but got %s", test.tags, test.renames, test.expected, renamed)
+			t.Fail()
+		}
+	}
+}
+
+func TestDataHandler_RenameTags(t *testing.T) {
+	dh := NewTestDataHandler()
+	defer dh.Close()
+
+	note := GetTestNoteMiniDoc()
+	note.Tags = "go vim"
+	todo := GetTestTodoMiniDoc()
+	todo.Tags = "golang"
+	url := GetTestUrlMiniDoc()
+	url.Tags = "rust"
+	dh.WriteAll([]MiniDoc{note, todo, url})
+
+	changed, err := dh.RenameTags(map[string]string{"go": "lang/go", "golang": "lang/go"})
+	if err != nil || changed != 2 {
+		t.Logf("expected 2 docs retagged but got %d: %v", changed, err)
+		t.Fail()
+	}
+
+	if _, err := dh.RenameTags(map[string]string{"lang": "lang/x"}); err == nil {
+		t.Log("renaming a tag into its own child should fail")
+		t.Fail()
+	}
+
+	result, _ := dh.Indexer.Search("tag:lang", 0, 0)
+	if len(result.Docs) != 2 {
+		t.Logf("tag:lang should match lang/go but found %d docs", len(result.Docs))
+		t.Fail()
+	}
+	result, _ = dh.Indexer.Search("tag:golang", 0, 0)
+	if len(result.Docs) != 0 {
+		t.Log("merged tag should be gone from the index")
+		t.Fail()
+	}
+}
@@ -0,0 +1,281 @@
+package minidoc
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const trashBucketName = "_trash"
+
+type TrashItem struct {
+	// DocID is the key of the doc in the trash, e.g. note:12
+	DocID       string
+	DeletedDate string
+	Doc         MiniDoc
+	// Err tells why Doc could only be read as a BaseDoc, e.g. its doctype is gone from the config file. Such a doc
+	// is listed so it can be purged but it cannot be restored
+	Err error
+}
+
+type trashRecord struct {
+	DeletedDate string          `json:"deleted_date"`
+	Doc         json.RawMessage `json:"doc"`
+}
+
+// Trash moves doc out of its doctype bucket into the trash bucket
+func (tx *BucketTx) Trash(doc MiniDoc) error {
+	key := toBytes(doc.GetID())
+	doctype := doc.GetType()
+
+	data := tx.kv.Get(doctype, key)
+	if data == nil {
+		msg := fmt.Sprintf("item not found in bucket[%s] with key[%d]", doctype, doc.GetID())
+		log.Errorf(msg)
+		return fmt.Errorf(msg)
+	}
+
+	record, err := json.Marshal(trashRecord{time.Now().Format(dateTimeFormat), data})
+	if err != nil {
+		log.Errorf("error while marshalling trash record for %s: %v", doc.GetIDString(), err)
+		return err
+	}
+
+	err = tx.kv.Put(trashBucketName, []byte(doc.GetIDString()), record)
+	if err != nil {
+		log.Errorf("error while moving %s to trash: %v", doc.GetIDString(), err)
+		return err
+	}
+
+	log.Debugf("trashing in bucket[%s] with key[%d]", doctype, doc.GetID())
+	return tx.kv.Delete(doctype, key)
+}
+
+// Restore moves a trashed doc back into its doctype bucket, upgrading it to the current schema on the way
+func (tx *BucketTx) Restore(docid string) (MiniDoc, error) {
+	data := tx.kv.Get(trashBucketName, []byte(docid))
+	if data == nil {
+		msg := fmt.Sprintf("item not found in trash with key[%s]", docid)
+		log.Errorf(msg)
+		return nil, fmt.Errorf(msg)
+	}
+
+	var record trashRecord
+	err := json.Unmarshal(data, &record)
+	if err != nil {
+		log.Errorf("error while unmarshalling trash record %s: %v", docid, err)
+		return nil, err
+	}
+	doctype := strings.Split(docid, ":")[0]
+	restored, err := migrateData(doctype, record.Doc)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := NewDoc(doctype)
+	if err != nil {
+		log.Errorf("instantiating %s", doctype)
+		return nil, err
+	}
+	err = json.Unmarshal(restored, doc)
+	if err != nil {
+		log.Errorf("error while unmarshalling restored %s: %v", docid, err)
+		return nil, err
+	}
+
+	err = tx.kv.Put(doctype, toBytes(doc.GetID()), restored)
+	if err != nil {
+		log.Errorf("error while restoring %s: %v", docid, err)
+		return nil, err
+	}
+
+	return doc, tx.kv.Delete(trashBucketName, []byte(docid))
+}
+
+// Purge removes a trashed doc and its revisions for good, going by docid alone so a trashed doc that no longer
+// decodes can be purged too
+func (tx *BucketTx) Purge(docid string) error {
+	if tx.kv.Get(trashBucketName, []byte(docid)) == nil {
+		msg := fmt.Sprintf("item not found in trash with key[%s]", docid)
+		log.Errorf(msg)
+		return fmt.Errorf(msg)
+	}
+	doctype, id, err := splitTrashKey(docid)
+	if err != nil {
+		return err
+	}
+
+	historyBucket := historyBucketName(doctype)
+	keys := [][]byte{}
+	err = tx.kv.ForEachPrefix(historyBucket, revisionKeyPrefix(id), func(k, v []byte) error {
+		keys = append(keys, append([]byte{}, k...))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if err := tx.kv.Delete(historyBucket, k); err != nil {
+			return err
+		}
+	}
+
+	log.Debugf("purging %s", docid)
+	return tx.kv.Delete(trashBucketName, []byte(docid))
+}
+
+// TrashItems returns every doc in the trash, a record that no longer decodes, e.g. of a doctype since removed
+// from the config file, comes back as a BaseDoc with Err set
+func (tx *BucketTx) TrashItems() ([]TrashItem, error) {
+	items := []TrashItem{}
+	err := tx.kv.ForEach(trashBucketName, func(k, v []byte) error {
+		item, err := toTrashItem(string(k), v)
+		if err != nil {
+			item = undecodedTrashItem(string(k), v, err)
+		}
+		items = append(items, item)
+		return nil
+	})
+	return items, err
+}
+
+// splitTrashKey reads the doctype and id off a trash key such as note:12
+func splitTrashKey(docid string) (string, uint32, error) {
+	parts := strings.Split(docid, ":")
+	if len(parts) != 2 {
+		return "", 0, fmt.Errorf("invalid trash key %s", docid)
+	}
+	id, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid trash key %s", docid)
+	}
+	return parts[0], uint32(id), nil
+}
+
+// undecodedTrashItem keeps what can be read of a trashed doc that does not decode as its doctype
+func undecodedTrashItem(docid string, data []byte, err error) TrashItem {
+	var record trashRecord
+	json.Unmarshal(data, &record)
+	doc := &BaseDoc{}
+	json.Unmarshal(record.Doc, doc)
+	if doctype, id, splitErr := splitTrashKey(docid); splitErr == nil {
+		doc.Type, doc.ID = doctype, id
+	}
+	log.Errorf("trashed %s does not decode: %v", docid, err)
+	return TrashItem{docid, record.DeletedDate, doc, err}
+}
+
+func toTrashItem(docid string, data []byte) (TrashItem, error) {
+	var record trashRecord
+	err := json.Unmarshal(data, &record)
+	if err != nil {
+		log.Errorf("error while unmarshalling trash record %s: %v", docid, err)
+		return TrashItem{}, err
+	}
+
+	doctype := strings.Split(docid, ":")[0]
+	doc, err := NewDoc(doctype)
+	if err != nil {
+		log.Errorf("instantiating %s", doctype)
+		return TrashItem{}, err
+	}
+
+	err = json.Unmarshal(record.Doc, doc)
+	if err != nil {
+		log.Errorf("error while unmarshalling trashed %s: %v", docid, err)
+		return TrashItem{}, err
+	}
+
+	return TrashItem{docid, record.DeletedDate, doc, nil}, nil
+}
+
+// ShowTrash lists trashed docs in the result list
+func (s *Search) ShowTrash() {
+	var items []TrashItem
+	err := s.App.DataHandler.Store.View(func(tx *BucketTx) error {
+		var err error
+		items, err = tx.TrashItems()
+		return err
+	})
+	if err != nil {
+		s.App.SetStatus("[black:red]reading trash: " + err.Error() + "[white]")
+		return
+	}
+
+	s.UpdateResult([]MiniDoc{})
+	if len(items) == 0 {
+		s.App.SetStatus("[white:darkcyan] trash is empty[white]")
+		return
+	}
+
+	s.TrashItems = items
+	for i, item := range items {
+		fragments := fmt.Sprintf("deleted %s  %s", item.DeletedDate, item.Doc.GetTitle())
+		if item.Err != nil {
+			fragments += "  cannot be restored: " + item.Err.Error()
+		}
+		cd := []CellData{
+			CellData{item.Doc.GetType(), item.Doc.GetIDString()},
+			CellData{false, " "},
+			CellData{IsToggled(item.Doc), toggleMark(item.Doc)},
+			CellData{item.Doc.GetID(), ""},
+		}
+		cd = append(cd, s.ResultList.ColumnCells(item.Doc)...)
+		cd = append(cd, CellData{fragments + cellpadding, fragments + cellpadding})
+		s.ResultList.SetColumnCells(i, cd)
+	}
+
+	s.ResultList.ScrollToBeginning()
+	s.SelectRow(0)
+	s.GoToSearchResult()
+}
+
+// IsTrashMode tells whether the result list is showing trashed docs instead of search results
+func (s *Search) IsTrashMode() bool {
+	return s.TrashItems != nil
+}
+
+// RestoreTrashed puts the trashed doc at row back into the store and the index
+func (s *Search) RestoreTrashed(row int) {
+	if row >= len(s.TrashItems) {
+		return
+	}
+	doc := s.TrashItems[row].Doc
+
+	err := s.App.DataHandler.Restore(doc)
+	if err != nil {
+		s.App.SetStatus("[black:red]restoring: " + err.Error() + "[white]")
+		return
+	}
+	s.ShowTrash()
+	s.App.SetStatus("[white:darkcyan] " + doc.GetIDString() + " restored[white]")
+}
+
+// PurgeTrashedConfirmation asks before the trashed doc at row is removed for good
+func (s *Search) PurgeTrashedConfirmation(row int) {
+	if row >= len(s.TrashItems) {
+		return
+	}
+	docid := s.TrashItems[row].DocID
+	ConfirmationModal(s.App, "Purge "+docid+" permanently?", func() {
+		s.PurgeTrashed(row)
+	})
+}
+
+// PurgeTrashed permanently removes the trashed doc at row
+func (s *Search) PurgeTrashed(row int) {
+	if row >= len(s.TrashItems) {
+		return
+	}
+	docid := s.TrashItems[row].DocID
+
+	err := s.App.DataHandler.Purge(docid)
+	if err != nil {
+		s.App.SetStatus("[black:red]purging: " + err.Error() + "[white]")
+		return
+	}
+	s.ShowTrash()
+	s.App.SetStatus("[white:darkcyan] " + docid + " purged[white]")
+}